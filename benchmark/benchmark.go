@@ -0,0 +1,287 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/wonderstone/change-point-detection/cpd"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+// * Method is a detector under test: Run gets the whole series and returns
+// * the common Result, so online and offline detectors are compared alike.
+type Method struct {
+	Name string
+	Run  func(data []float64) cpd.Result
+}
+
+// * Row is the outcome of one method on one dataset.
+type Row struct {
+	Dataset, Method string
+	N               int
+	Changepoints    []int
+	evaluation.Report
+	Runtime time.Duration
+	// Alloc is the number of bytes allocated by the run
+	Alloc uint64
+	// Err is the panic of a failed run, its metrics are NaN
+	Err string
+}
+
+// * Config is the setting of the metrics.
+type Config struct {
+	// Margin is the tolerance of precision, recall and F1
+	Margin int
+	// DelayMargin is the horizon of the detection delay, from the Confirmed steps
+	DelayMargin int
+}
+
+// DefaultConfig is a 5 steps margin and a 50 steps delay horizon
+func DefaultConfig() Config {
+	return Config{Margin: 5, DelayMargin: 50}
+}
+
+// Run runs every method over every dataset, one after the other so that
+// the runtime and the allocations of a run are its own. A method which
+// panics gives a row with Err and the other runs go on.
+func Run(datasets []Dataset, methods []Method, cfg Config) []Row {
+	rows := make([]Row, 0, len(datasets)*len(methods))
+	for _, ds := range datasets {
+		data := carryForward(ds.Data)
+		for _, m := range methods {
+			rows = append(rows, runOne(ds, data, m, cfg))
+		}
+	}
+	return rows
+}
+
+// runOne runs the method over data, the dataset without its missing values
+func runOne(ds Dataset, data []float64, m Method, cfg Config) (row Row) {
+	row = Row{Dataset: ds.Name, Method: m.Name, N: len(ds.Data)}
+	defer func() {
+		if r := recover(); r != nil {
+			row.Changepoints = nil
+			row.Report = meanReport(nil)
+			row.Err = fmt.Sprint(r)
+		}
+	}()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	res := m.Run(data)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	row.Changepoints = res.Changepoints()
+	row.Runtime = elapsed
+	row.Alloc = after.TotalAlloc - before.TotalAlloc
+	alarms := make([]int, 0, len(res.Events))
+	for _, e := range res.Events {
+		if e.Kind == cpd.Changepoint {
+			alarms = append(alarms, e.Confirmed)
+		}
+	}
+	// @ the mean of the metrics over the annotators
+	reports := make([]evaluation.Report, 0, len(ds.Annotations))
+	for _, truth := range ds.Annotations {
+		r := evaluation.Evaluate(row.Changepoints, truth, row.N, cfg.Margin)
+		r.MeanDelay = evaluation.MeanDelay(alarms, truth, cfg.DelayMargin)
+		reports = append(reports, r)
+	}
+	row.Report = meanReport(reports)
+	return row
+}
+
+// meanReport returns the mean of each metric over its finite values,
+// e.g. the delay of the detected changepoints only. Without finite values
+// it is +Inf if there is one, e.g. the Hausdorff distance of no detection, or NaN.
+func meanReport(reports []evaluation.Report) evaluation.Report {
+	fields := func(r *evaluation.Report) []*float64 {
+		return []*float64{&r.Precision, &r.Recall, &r.F1, &r.Covering, &r.RandIndex, &r.Hausdorff, &r.MeanDelay}
+	}
+	var res evaluation.Report
+	for i, f := range fields(&res) {
+		sum, count := 0.0, 0
+		*f = math.NaN()
+		for j := range reports {
+			x := *fields(&reports[j])[i]
+			switch {
+			case math.IsInf(x, 0):
+				*f = x
+			case !math.IsNaN(x):
+				sum += x
+				count++
+			}
+		}
+		if count > 0 {
+			*f = sum / float64(count)
+		}
+	}
+	return res
+}
+
+// Summary returns one row per method with the mean of the metrics over the
+// datasets, see meanReport, and the total runtime and allocations.
+// The failed runs have no metrics, Err counts them.
+// The rows are sorted by F1.
+func Summary(rows []Row) []Row {
+	index := map[string]int{}
+	var res []Row
+	var reports [][]evaluation.Report
+	var failed []int
+	for _, r := range rows {
+		i, ok := index[r.Method]
+		if !ok {
+			i = len(res)
+			index[r.Method] = i
+			res = append(res, Row{Dataset: "mean", Method: r.Method})
+			reports = append(reports, nil)
+			failed = append(failed, 0)
+		}
+		if r.Err != "" {
+			failed[i]++
+		}
+		res[i].N += r.N
+		res[i].Runtime += r.Runtime
+		res[i].Alloc += r.Alloc
+		reports[i] = append(reports[i], r.Report)
+	}
+	for i := range res {
+		res[i].Report = meanReport(reports[i])
+		if failed[i] > 0 {
+			res[i].Err = fmt.Sprintf("%d failed runs", failed[i])
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].F1 > res[j].F1 })
+	return res
+}
+
+// * The default methods use the settings of the tests of cpd: a vague
+// * Normal-Gamma prior and a 250 steps expected segment length for BOCPD,
+// * MBIC penalties for the offline searches.
+
+func studentT() *cpd.StudentT_Bayesian_Update {
+	return cpd.NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+}
+
+// streaming wraps a Detector into a Method
+func streaming(name string, newDetector func() cpd.Detector) Method {
+	return Method{Name: name, Run: func(data []float64) cpd.Result {
+		return cpd.Result{Method: name, N: len(data), Events: cpd.RunDetector(newDetector(), data)}
+	}}
+}
+
+// DefaultMethods returns every detector of the package with its default setting
+func DefaultMethods() []Method {
+	return []Method{
+		{Name: "OCPD", Run: func(data []float64) cpd.Result {
+			d := cpd.NewOCPD(250, cpd.ConstantHazardSlice, studentT()).SetConfirmation(25, 0.5)
+			cpd.RunDetector(d, data)
+			return d.Result()
+		}},
+		{Name: "OnlineChangepointDetection", Run: func(data []float64) cpd.Result {
+			R, _ := cpd.OnlineChangepointDetection(data, 250, cpd.ConstantHazard, studentT())
+			return cpd.ResultFromR(&R, 25, 0.5)
+		}},
+		{Name: "ParticleOCPD", Run: func(data []float64) cpd.Result {
+			d := cpd.NewParticleOCPD(250, cpd.ConstantHazardSlice, studentT(), 50, 1).SetConfirmation(25, 0.5)
+			cpd.RunDetector(d, data)
+			return d.Result()
+		}},
+		{Name: "OfflineBOCPD", Run: func(data []float64) cpd.Result {
+			return cpd.NewOfflineBOCPD(data, 250, cpd.ConstantHazardSlice, studentT()).Result(0.5)
+		}},
+		{Name: "MAP", Run: func(data []float64) cpd.Result {
+			return cpd.NewOfflineBOCPD(data, 250, cpd.ConstantHazardSlice, studentT()).MAP(data, studentT())
+		}},
+		{Name: "PELT-Mean", Run: func(data []float64) cpd.Result {
+			return cpd.PELT(data, cpd.NewMeanCost(), cpd.Penalty{Kind: cpd.MBIC}, 5)
+		}},
+		{Name: "PELT-MeanVar", Run: func(data []float64) cpd.Result {
+			return cpd.PELT(data, cpd.NewMeanVarCost(), cpd.Penalty{Kind: cpd.MBIC}, 5)
+		}},
+		{Name: "BinarySegmentation", Run: func(data []float64) cpd.Result {
+			return cpd.BinarySegmentation(data, 0, cpd.ThresholdFromPenalty(cpd.Penalty{Kind: cpd.MBIC}, len(data)), 5)
+		}},
+		{Name: "WildBinarySegmentation", Run: func(data []float64) cpd.Result {
+			return cpd.WildBinarySegmentation(data, 0, cpd.ThresholdFromPenalty(cpd.Penalty{Kind: cpd.MBIC}, len(data)), 5, 500, 1)
+		}},
+		{Name: "KCP", Run: func(data []float64) cpd.Result {
+			return cpd.KCP(data, cpd.Penalty{Kind: cpd.ManualPenalty, Value: 2}, 10)
+		}},
+		{Name: "EDivisive", Run: func(data []float64) cpd.Result {
+			return cpd.EDivisive(data, 1, 0, 30, 49, 0.05, 1)
+		}},
+		{Name: "BayesianBlocks", Run: func(data []float64) cpd.Result {
+			t := make([]float64, len(data))
+			sigma := make([]float64, len(data))
+			s := noiseStd(data)
+			for i := range data {
+				t[i], sigma[i] = float64(i), s
+			}
+			return cpd.BayesianBlocksMeasures(t, data, sigma, 0)
+		}},
+		streaming("CUSUM", func() cpd.Detector { return cpd.NewCUSUM(0.5, 8, cpd.TwoSided, 20) }),
+		streaming("PageHinkley", func() cpd.Detector { return cpd.NewPageHinkley(0.5, 50, cpd.TwoSided, 20) }),
+		streaming("ADWIN", func() cpd.Detector { return cpd.NewADWIN(0.002, 5, 5, 32) }),
+		streaming("TwoSample-KS", func() cpd.Detector { return cpd.NewTwoSampleDetector(cpd.KS, 40, 20, 1e-4, 0, 1) }),
+		streaming("TwoSample-MMD", func() cpd.Detector { return cpd.NewTwoSampleDetector(cpd.MMD, 40, 20, 1e-4, 0, 1) }),
+	}
+}
+
+// carryForward returns the data with each NaN or infinite value replaced by
+// the last finite one, the leading ones by the first finite one, 0 without
+// any: most of the detectors have no notion of a missing value.
+// The data are copied only if there is something to replace.
+func carryForward(data []float64) []float64 {
+	finite := func(x float64) bool { return !math.IsNaN(x) && !math.IsInf(x, 0) }
+	last := 0.0
+	for _, x := range data {
+		if finite(x) {
+			last = x
+			break
+		}
+	}
+	res, copied := data, false
+	for i, x := range data {
+		if finite(x) {
+			last = x
+			continue
+		}
+		if !copied {
+			res, copied = append([]float64{}, data...), true
+		}
+		res[i] = last
+	}
+	return res
+}
+
+// SelectMethods keeps the methods with the names, in the order of the names
+func SelectMethods(methods []Method, names []string) []Method {
+	var res []Method
+	for _, name := range names {
+		for _, m := range methods {
+			if m.Name == name {
+				res = append(res, m)
+			}
+		}
+	}
+	return res
+}
+
+// noiseStd estimates the noise std from the MAD of the first differences
+func noiseStd(data []float64) float64 {
+	if len(data) < 2 {
+		return 1
+	}
+	diffs := make([]float64, len(data)-1)
+	for i := range diffs {
+		diffs[i] = math.Abs(data[i+1] - data[i])
+	}
+	sort.Float64s(diffs)
+	return math.Max(diffs[len(diffs)/2]/(0.6745*math.Sqrt2), 1e-9)
+}
@@ -0,0 +1,104 @@
+package benchmark
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/cpd"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+// test the rows and the summary of a run
+func TestRun(t *testing.T) {
+	datasets := DefaultScenarios(1)[:2]
+	methods := SelectMethods(DefaultMethods(), []string{"PELT-MeanVar", "CUSUM"})
+	assert.Equal(t, "PELT-MeanVar", methods[0].Name)
+	rows := Run(datasets, methods, DefaultConfig())
+	assert.Equal(t, 4, len(rows))
+
+	// PELT finds the mean and the variance changes
+	assert.Equal(t, "mean-shift", rows[0].Dataset)
+	assert.Equal(t, []int{150, 350, 470}, rows[0].Changepoints)
+	assert.Equal(t, 1.0, rows[0].F1)
+	assert.Equal(t, 1.0, rows[2].F1)
+	// the offline detectors have no delay within the horizon
+	assert.True(t, math.IsNaN(rows[0].MeanDelay))
+	assert.False(t, math.IsNaN(rows[1].MeanDelay))
+	assert.Greater(t, rows[0].Alloc, uint64(0))
+	assert.Greater(t, rows[0].Runtime.Nanoseconds(), int64(0))
+
+	summary := Summary(rows)
+	assert.Equal(t, 2, len(summary))
+	assert.Equal(t, "PELT-MeanVar", summary[0].Method)
+	assert.Equal(t, "mean", summary[0].Dataset)
+	assert.Equal(t, rows[0].N+rows[2].N, summary[0].N)
+	assert.Equal(t, 1.0, summary[0].F1)
+}
+
+// test the mean over the finite values
+func TestMeanReport(t *testing.T) {
+	r := meanReport([]evaluation.Report{
+		{F1: 1, Hausdorff: math.Inf(1), MeanDelay: math.NaN()},
+		{F1: 0.5, Hausdorff: 4, MeanDelay: math.NaN()},
+	})
+	assert.Equal(t, 0.75, r.F1)
+	assert.Equal(t, 4.0, r.Hausdorff)
+	assert.True(t, math.IsNaN(r.MeanDelay))
+	assert.True(t, math.IsInf(meanReport([]evaluation.Report{{Hausdorff: math.Inf(1)}}).Hausdorff, 1))
+}
+
+// test a custom method is scored against the annotators
+func TestRunAnnotators(t *testing.T) {
+	ds := Dataset{Name: "two", Data: make([]float64, 100), Annotations: [][]int{{50}, {40}}}
+	fixed := Method{Name: "fixed", Run: func(data []float64) cpd.Result {
+		return cpd.NewOfflineResult("fixed", len(data), []int{50}, nil)
+	}}
+	rows := Run([]Dataset{ds}, []Method{fixed}, DefaultConfig())
+	assert.Equal(t, 0.5, rows[0].F1)
+	assert.Equal(t, 5.0, rows[0].Hausdorff)
+}
+
+// test a panic is an error row and the other runs go on
+func TestRunRecover(t *testing.T) {
+	ds := Labelled("x", []float64{1, math.NaN(), 3, math.Inf(1)}, []int{2})
+	failing := Method{Name: "failing", Run: func(data []float64) cpd.Result { panic("boom") }}
+	var seen []float64
+	spy := Method{Name: "spy", Run: func(data []float64) cpd.Result {
+		seen = data
+		return cpd.NewOfflineResult("spy", len(data), nil, nil)
+	}}
+	rows := Run([]Dataset{ds}, []Method{failing, spy}, DefaultConfig())
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "boom", rows[0].Err)
+	assert.True(t, math.IsNaN(rows[0].F1))
+	assert.Equal(t, "", rows[1].Err)
+	// the missing values are carried forward, the dataset is left as it is
+	assert.Equal(t, []float64{1, 1, 3, 3}, seen)
+	assert.True(t, math.IsNaN(ds.Data[1]))
+	assert.Equal(t, []float64{2, 2}, carryForward([]float64{math.NaN(), 2}))
+	for _, r := range Summary(rows) {
+		if r.Method == "failing" {
+			assert.Equal(t, "1 failed runs", r.Err)
+		}
+	}
+}
+
+// test every default method runs over the scenario files, with their outliers and missing values
+func TestRunScenarioFiles(t *testing.T) {
+	files, err := filepath.Glob("../data/scenarios/*")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+	var datasets []Dataset
+	for _, file := range files {
+		ds, err := LoadScenario(file)
+		assert.Nil(t, err)
+		datasets = append(datasets, ds)
+	}
+	rows := Run(datasets, DefaultMethods(), DefaultConfig())
+	assert.Equal(t, len(datasets)*len(DefaultMethods()), len(rows))
+	for _, r := range rows {
+		assert.Equal(t, "", r.Err, "%s on %s", r.Method, r.Dataset)
+	}
+}
@@ -0,0 +1,159 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wonderstone/change-point-detection/cpd"
+	"github.com/wonderstone/change-point-detection/datasets"
+)
+
+// * Dataset is a labelled series. Annotations are the changepoints of one or
+// * more annotators, the metrics are averaged over them.
+type Dataset struct {
+	Name        string
+	Data        []float64
+	Annotations [][]int
+}
+
+// Labelled returns a dataset with one annotation
+func Labelled(name string, data []float64, truth []int) Dataset {
+	return Dataset{Name: name, Data: data, Annotations: [][]int{truth}}
+}
+
+// FromSimulation returns the dataset of a univariate simulation
+func FromSimulation(name string, sim cpd.Simulation) Dataset {
+	return Labelled(name, sim.Data, sim.Changepoints)
+}
+
+// FromSeries returns the dataset of a bundled series, see package datasets
+func FromSeries(s datasets.Series) Dataset {
+	return Labelled(s.Name, s.Data, s.Changepoints)
+}
+
+// Embedded returns the bundled series with the names, all of them without names
+func Embedded(names ...string) ([]Dataset, error) {
+	if len(names) == 0 {
+		names = datasets.Names()
+	}
+	res := make([]Dataset, len(names))
+	for i, name := range names {
+		s, err := datasets.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = FromSeries(s)
+	}
+	return res, nil
+}
+
+// Unique drops the datasets with the name and the data of an earlier one,
+// e.g. a csv file which is also bundled
+func Unique(datasets []Dataset) []Dataset {
+	var res []Dataset
+	for _, ds := range datasets {
+		dup := false
+		for _, prev := range res {
+			if prev.Name == ds.Name && reflect.DeepEqual(prev.Data, ds.Data) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			res = append(res, ds)
+		}
+	}
+	return res
+}
+
+// LoadScenario simulates a scenario file, the name is the scenario name or the file name
+func LoadScenario(path string) (Dataset, error) {
+	spec, err := cpd.LoadScenario(path)
+	if err != nil {
+		return Dataset{}, err
+	}
+	sim, err := spec.Simulate()
+	if err != nil {
+		return Dataset{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if sim.Series != nil {
+		return Dataset{}, fmt.Errorf("%s: multivariate scenarios are not supported", path)
+	}
+	name := spec.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return FromSimulation(name, sim), nil
+}
+
+// AnnotationsPath returns the annotation file of a csv: data.csv -> data.annotations.json
+func AnnotationsPath(csvPath string) string {
+	return strings.TrimSuffix(csvPath, filepath.Ext(csvPath)) + ".annotations.json"
+}
+
+// LoadCSV reads the first column of a csv and its annotation file, which is
+// a list of changepoints or an object of the lists of the annotators:
+// [58, 132] or {"1": [58, 132], "2": [60]}
+func LoadCSV(path string) (Dataset, error) {
+	raw, err := os.ReadFile(AnnotationsPath(path))
+	if err != nil {
+		return Dataset{}, err
+	}
+	annotations, err := parseAnnotations(raw)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("%s: %w", AnnotationsPath(path), err)
+	}
+	series, err := cpd.ReadCSV(path, cpd.CSVOptions{Indices: []int{0}})
+	if err != nil {
+		return Dataset{}, err
+	}
+	return Dataset{
+		Name:        strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Data:        series[0].Data,
+		Annotations: annotations,
+	}, nil
+}
+
+func parseAnnotations(raw []byte) ([][]int, error) {
+	var list []int
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return [][]int{list}, nil
+	}
+	var byAnnotator map[string][]int
+	if err := json.Unmarshal(raw, &byAnnotator); err != nil {
+		return nil, fmt.Errorf("annotations must be a list or an object of lists")
+	}
+	if len(byAnnotator) == 0 {
+		return nil, fmt.Errorf("no annotations")
+	}
+	// @ the annotators in a fixed order
+	names := make([]string, 0, len(byAnnotator))
+	for name := range byAnnotator {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([][]int, 0, len(names))
+	for _, name := range names {
+		res = append(res, byAnnotator[name])
+	}
+	return res, nil
+}
+
+// DefaultScenarios returns synthetic datasets for the mean, variance and
+// trend changes and the mean changes with outliers
+func DefaultScenarios(seed int64) []Dataset {
+	lengths := []int{150, 200, 120, 180}
+	outliers := cpd.SimulateMeanShift(lengths, []float64{0, 4, -2, 3}, 1, seed+3)
+	outliers.AddOutliers(0.01, 8, seed+4)
+	return []Dataset{
+		FromSimulation("mean-shift", cpd.SimulateMeanShift(lengths, []float64{0, 4, -2, 3}, 1, seed)),
+		FromSimulation("variance", cpd.SimulateVariance(lengths, []float64{1, 4, 1, 0.3}, 0, seed+1)),
+		FromSimulation("trend", cpd.SimulateTrend(lengths, []float64{0, 0.05, -0.05, 0}, 0.5, seed+2)),
+		FromSimulation("mean-shift-outliers", outliers),
+	}
+}
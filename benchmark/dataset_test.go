@@ -0,0 +1,63 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCSV(t *testing.T) {
+	assert.Equal(t, "../data/data_output.annotations.json", AnnotationsPath("../data/data_output.csv"))
+	ds, err := LoadCSV("../data/data_output.csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "data_output", ds.Name)
+	assert.Equal(t, 856, len(ds.Data))
+	assert.Equal(t, [][]int{{58, 132, 249, 402, 539, 668}}, ds.Annotations)
+
+	// the annotators are sorted by name
+	annotations, err := parseAnnotations([]byte(`{"b": [60], "a": [58, 132]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]int{{58, 132}, {60}}, annotations)
+	_, err = parseAnnotations([]byte(`"58"`))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "x.csv")
+	assert.Nil(t, os.WriteFile(path, []byte("1\n2\n"), 0644))
+	_, err = LoadCSV(path)
+	assert.Error(t, err)
+}
+
+func TestLoadScenario(t *testing.T) {
+	ds, err := LoadScenario("../data/scenarios/data_input.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "data_input", ds.Name)
+	assert.Equal(t, 2153, len(ds.Data))
+	assert.Equal(t, [][]int{{783, 1301, 1831, 1891}}, ds.Annotations)
+
+	for _, ds := range DefaultScenarios(1) {
+		assert.Equal(t, 650, len(ds.Data))
+		assert.Equal(t, [][]int{{150, 350, 470}}, ds.Annotations)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	all, err := Embedded()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(all))
+	nile, err := Embedded("nile")
+	assert.Nil(t, err)
+	assert.Equal(t, "nile", nile[0].Name)
+	assert.Equal(t, [][]int{{28}}, nile[0].Annotations)
+
+	// the csv of the demo is also bundled, it is benchmarked once
+	demo, err := LoadCSV("../data/data_output.csv")
+	assert.Nil(t, err)
+	unique := Unique(append(all, demo, Labelled("data_output", []float64{1}, nil)))
+	assert.Equal(t, 4, len(unique))
+	assert.Equal(t, all, unique[:3])
+	_, err = Embedded("well-log")
+	assert.Error(t, err)
+}
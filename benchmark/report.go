@@ -0,0 +1,124 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// columns are the headers of the tables
+var columns = []string{"dataset", "method", "n", "precision", "recall", "f1", "covering", "rand_index", "hausdorff", "mean_delay", "runtime_ms", "alloc_kb", "error"}
+
+func (r Row) cells() []string {
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', 3, 64) }
+	return []string{
+		r.Dataset, r.Method, strconv.Itoa(r.N),
+		f(r.Precision), f(r.Recall), f(r.F1), f(r.Covering), f(r.RandIndex),
+		f(r.Hausdorff), f(r.MeanDelay),
+		f(float64(r.Runtime.Microseconds()) / 1000), strconv.FormatUint(r.Alloc/1024, 10),
+		r.Err,
+	}
+}
+
+// WriteMarkdown writes the summary per method, then the rows
+func WriteMarkdown(w io.Writer, rows []Row) error {
+	table := func(title string, rows []Row) error {
+		if _, err := fmt.Fprintf(w, "## %s\n\n|", title); err != nil {
+			return err
+		}
+		for _, c := range columns {
+			fmt.Fprintf(w, " %s |", c)
+		}
+		fmt.Fprint(w, "\n|")
+		for range columns {
+			fmt.Fprint(w, " --- |")
+		}
+		fmt.Fprintln(w)
+		for _, r := range rows {
+			fmt.Fprint(w, "|")
+			for _, c := range r.cells() {
+				fmt.Fprintf(w, " %s |", c)
+			}
+			fmt.Fprintln(w)
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	if err := table("Summary", Summary(rows)); err != nil {
+		return err
+	}
+	return table("Results", rows)
+}
+
+// WriteCSV writes the rows with a header
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := writer.Write(r.cells()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// jsonRow is Row for json, NaN and Inf are null
+type jsonRow struct {
+	Dataset      string   `json:"dataset"`
+	Method       string   `json:"method"`
+	N            int      `json:"n"`
+	Changepoints []int    `json:"changepoints"`
+	Precision    *float64 `json:"precision"`
+	Recall       *float64 `json:"recall"`
+	F1           *float64 `json:"f1"`
+	Covering     *float64 `json:"covering"`
+	RandIndex    *float64 `json:"rand_index"`
+	Hausdorff    *float64 `json:"hausdorff"`
+	MeanDelay    *float64 `json:"mean_delay"`
+	RuntimeMs    float64  `json:"runtime_ms"`
+	Alloc        uint64   `json:"alloc_bytes"`
+	Err          string   `json:"error,omitempty"`
+}
+
+// WriteJSON writes the rows as a json array
+func WriteJSON(w io.Writer, rows []Row) error {
+	finite := func(x float64) *float64 {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+		return &x
+	}
+	out := make([]jsonRow, len(rows))
+	for i, r := range rows {
+		out[i] = jsonRow{
+			Dataset: r.Dataset, Method: r.Method, N: r.N, Changepoints: r.Changepoints,
+			Precision: finite(r.Precision), Recall: finite(r.Recall), F1: finite(r.F1),
+			Covering: finite(r.Covering), RandIndex: finite(r.RandIndex),
+			Hausdorff: finite(r.Hausdorff), MeanDelay: finite(r.MeanDelay),
+			RuntimeMs: float64(r.Runtime.Microseconds()) / 1000, Alloc: r.Alloc, Err: r.Err,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// Write writes the rows in the format: markdown, csv or json
+func Write(w io.Writer, rows []Row, format string) error {
+	switch format {
+	case "markdown", "md":
+		return WriteMarkdown(w, rows)
+	case "csv":
+		return WriteCSV(w, rows)
+	case "json":
+		return WriteJSON(w, rows)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,45 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+func TestWrite(t *testing.T) {
+	rows := []Row{
+		{Dataset: "a", Method: "m", N: 10, Changepoints: []int{5}, Runtime: 1500 * time.Microsecond, Alloc: 2048,
+			Report: evaluation.Report{Precision: 1, Recall: 1, F1: 1, Covering: 1, RandIndex: 1, MeanDelay: math.NaN()}},
+		{Dataset: "b", Method: "m", N: 10,
+			Report: evaluation.Report{Precision: 1, Hausdorff: math.Inf(1), MeanDelay: math.NaN()}},
+	}
+
+	var md bytes.Buffer
+	assert.Nil(t, Write(&md, rows, "markdown"))
+	assert.True(t, strings.HasPrefix(md.String(), "## Summary\n\n| dataset | method | n |"))
+	assert.Contains(t, md.String(), "| a | m | 10 | 1.000 | 1.000 | 1.000 |")
+	assert.Contains(t, md.String(), "| mean | m | 20 | 1.000 | 0.500 | 0.500 |")
+
+	var csv bytes.Buffer
+	assert.Nil(t, Write(&csv, rows, "csv"))
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "a,m,10,1.000,1.000,1.000,1.000,1.000,0.000,NaN,1.500,2,", lines[1])
+
+	var js bytes.Buffer
+	assert.Nil(t, Write(&js, rows, "json"))
+	var out []map[string]any
+	assert.Nil(t, json.Unmarshal(js.Bytes(), &out))
+	assert.Equal(t, 2, len(out))
+	assert.Nil(t, out[0]["mean_delay"])
+	assert.Nil(t, out[1]["hausdorff"])
+	assert.Equal(t, 1.5, out[0]["runtime_ms"])
+
+	assert.Error(t, Write(&js, rows, "xml"))
+}
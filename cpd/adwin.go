@@ -0,0 +1,159 @@
+package cpd
+
+import "math"
+
+// * adwinBucket summarizes n consecutive values: their total and the sum of
+// * squared deviations from their mean.
+type adwinBucket struct {
+	n        int
+	total    float64
+	variance float64
+}
+
+// merge returns the bucket of the two buckets together
+func (b adwinBucket) merge(o adwinBucket) adwinBucket {
+	n := b.n + o.n
+	d := b.total/float64(b.n) - o.total/float64(o.n)
+	return adwinBucket{
+		n:        n,
+		total:    b.total + o.total,
+		variance: b.variance + o.variance + float64(b.n*o.n)/float64(n)*d*d,
+	}
+}
+
+// * ADWIN is the adaptive windowing drift detector of Bifet and Gavalda (2007).
+// * The window keeps growing while the data are stationary, and it drops the
+// * older part as soon as two sub-windows have different enough means.
+// * delta bounds the probability of a false cut.
+// * The window is stored as an exponential histogram: row i holds at most
+// * maxBuckets buckets of 2^i values, so the memory is O(maxBuckets log W).
+type ADWIN struct {
+	delta      float64
+	maxBuckets int
+	minWindow  int
+	clock      int
+	// rows[i] holds the buckets of 2^i values, the oldest first
+	rows     [][]adwinBucket
+	window   adwinBucket
+	step     int
+	lastDiff float64
+}
+
+// NewADWIN returns a new ADWIN. The cut is checked every clock steps between
+// sub-windows at least minWindow long. Common choices are delta = 0.002,
+// maxBuckets = 5, minWindow = 5 and clock = 32.
+func NewADWIN(delta float64, maxBuckets, minWindow, clock int) *ADWIN {
+	if delta <= 0 || delta >= 1 || maxBuckets < 2 || minWindow < 1 || clock < 1 {
+		panic("ADWIN needs 0 < delta < 1, maxBuckets >= 2, minWindow >= 1 and clock >= 1")
+	}
+	return &ADWIN{delta: delta, maxBuckets: maxBuckets, minWindow: minWindow, clock: clock}
+}
+
+// Update makes ADWIN a Detector, the event is the new start of the window
+func (ad *ADWIN) Update(x float64) []Event {
+	t := ad.step
+	ad.step++
+	// @ 1. insert x as a new bucket of one value
+	b := adwinBucket{n: 1, total: x}
+	if ad.window.n == 0 {
+		ad.window = b
+	} else {
+		ad.window = ad.window.merge(b)
+	}
+	if len(ad.rows) == 0 {
+		ad.rows = append(ad.rows, nil)
+	}
+	ad.rows[0] = append(ad.rows[0], b)
+	// @ 2. compress the rows with too many buckets
+	for i := 0; i < len(ad.rows); i++ {
+		if len(ad.rows[i]) <= ad.maxBuckets {
+			break
+		}
+		if i+1 == len(ad.rows) {
+			ad.rows = append(ad.rows, nil)
+		}
+		ad.rows[i+1] = append(ad.rows[i+1], ad.rows[i][0].merge(ad.rows[i][1]))
+		ad.rows[i] = ad.rows[i][2:]
+	}
+	// @ 3. drop the oldest buckets while there is a cut
+	if ad.step%ad.clock != 0 {
+		return nil
+	}
+	cut := false
+	for ad.window.n > 2*ad.minWindow && ad.detectCut() {
+		ad.dropOldest()
+		cut = true
+	}
+	if !cut {
+		return nil
+	}
+	start := ad.step - ad.window.n
+	return []Event{{Kind: Changepoint, Index: start, Detected: t, Confirmed: t, Latency: t - start, Score: ad.lastDiff}}
+}
+
+// detectCut checks all the splits between buckets, from the oldest one
+func (ad *ADWIN) detectCut() bool {
+	n := float64(ad.window.n)
+	sigma2 := ad.window.variance / n
+	dd := math.Log(2 * math.Log(n) / ad.delta)
+	var old adwinBucket
+	for i := len(ad.rows) - 1; i >= 0; i-- {
+		for _, b := range ad.rows[i] {
+			if old.n == 0 {
+				old = b
+			} else {
+				old = old.merge(b)
+			}
+			n0, n1 := float64(old.n), n-float64(old.n)
+			if n1 < float64(ad.minWindow) {
+				return false
+			}
+			if n0 < float64(ad.minWindow) {
+				continue
+			}
+			// @ eps = sqrt(2/m sigma2 ln(2/delta')) + 2/(3m) ln(2/delta'), m the harmonic mean
+			m := 1 / (1/n0 + 1/n1)
+			eps := math.Sqrt(2/m*sigma2*dd) + 2/(3*m)*dd
+			diff := math.Abs(old.total/n0 - (ad.window.total-old.total)/n1)
+			if diff > eps {
+				ad.lastDiff = diff
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dropOldest removes the oldest bucket from the window
+func (ad *ADWIN) dropOldest() {
+	i := len(ad.rows) - 1
+	b := ad.rows[i][0]
+	ad.rows[i] = ad.rows[i][1:]
+	if len(ad.rows[i]) == 0 {
+		ad.rows = ad.rows[:i]
+	}
+	// @ remove b from the window: S = S_b + S_rest + n_b n_rest / n (mu_b - mu_rest)^2
+	rest := ad.window.n - b.n
+	restTotal := ad.window.total - b.total
+	d := b.total/float64(b.n) - restTotal/float64(rest)
+	ad.window = adwinBucket{
+		n:        rest,
+		total:    restTotal,
+		variance: math.Max(0, ad.window.variance-b.variance-float64(b.n*rest)/float64(ad.window.n)*d*d),
+	}
+}
+
+// WindowLength returns the current length of the adaptive window
+func (ad *ADWIN) WindowLength() int {
+	return ad.window.n
+}
+
+// State returns the window length as the run length and the window estimate
+func (ad *ADWIN) State() State {
+	s := State{Step: ad.step, RunLength: ad.window.n, Statistic: ad.lastDiff}
+	if ad.window.n > 0 {
+		s.Mean = ad.window.total / float64(ad.window.n)
+		s.Variance = ad.window.variance / float64(ad.window.n)
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package cpd
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test ADWIN cuts the window after the error rate of a model goes up
+func TestADWIN(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	errors := make([]float64, 0)
+	for i := 0; i < 3000; i++ {
+		p := 0.1
+		if i >= 2000 {
+			p = 0.4
+		}
+		e := 0.0
+		if rng.Float64() < p {
+			e = 1
+		}
+		errors = append(errors, e)
+	}
+
+	ad := NewADWIN(0.002, 5, 5, 32)
+	var d Detector = ad
+	events := RunDetector(d, errors[:2000])
+	// no false cut in the stationary part
+	assert.Empty(t, events)
+	assert.Equal(t, 2000, ad.WindowLength())
+	assert.InDelta(t, 0.1, d.State().Mean, 0.02)
+	// the buckets are compressed
+	buckets := 0
+	for _, row := range ad.rows {
+		buckets += len(row)
+	}
+	assert.Less(t, buckets, 60)
+
+	events = RunDetector(d, errors[2000:])
+	assert.NotEmpty(t, events)
+	assert.Less(t, events[0].Detected-2000, 100)
+	// the later cuts move the start of the window to the change
+	assert.InDelta(t, 2000, events[len(events)-1].Index, 100)
+	// the window only keeps the new regime
+	assert.LessOrEqual(t, ad.WindowLength(), 1100)
+	assert.InDelta(t, 0.4, d.State().Mean, 0.05)
+	assert.Equal(t, 3000, d.State().Step)
+}
@@ -0,0 +1,192 @@
+package cpd
+
+import (
+	"math"
+	"sort"
+)
+
+// * Monitor is a stopping rule over a stream: a fresh detector and its
+// * decision statistic after each update. It alarms at the first step where
+// * the statistic is at least the threshold, so the threshold is calibrated
+// * apart from the detector.
+type Monitor struct {
+	New       func() Detector
+	Statistic func(d Detector) float64
+}
+
+// OCPDMonitor watches the probability that the segment started within the
+// last k values, see OCPD.ChangeProbability; k = 1 is P(r_t=0)
+func OCPDMonitor(newOCPD func() *OCPD, k int) Monitor {
+	if k < 1 {
+		panic("k must be positive")
+	}
+	return Monitor{
+		New:       func() Detector { return newOCPD() },
+		Statistic: func(d Detector) float64 { return d.(*OCPD).ChangeProbability(k) },
+	}
+}
+
+// StateMonitor watches State().Statistic. The control charts reset after
+// their own alarm, so build them with an infinite threshold, e.g.
+// NewCUSUM(0.5, math.Inf(1), TwoSided, 20).
+func StateMonitor(newDetector func() Detector) Monitor {
+	return Monitor{
+		New:       newDetector,
+		Statistic: func(d Detector) float64 { return d.State().Statistic },
+	}
+}
+
+// path returns the running max of the statistic over the data, it stops at
+// the first step where the statistic is at least stop
+func (m Monitor) path(data []float64, stop float64) []float64 {
+	d := m.New()
+	res := make([]float64, 0, len(data))
+	best := math.Inf(-1)
+	for _, x := range data {
+		d.Update(x)
+		best = math.Max(best, m.Statistic(d))
+		res = append(res, best)
+		if best >= stop {
+			break
+		}
+	}
+	return res
+}
+
+// firstAlarm returns the first step of the running max at least threshold, -1 if none
+func firstAlarm(path []float64, threshold float64) int {
+	t := sort.Search(len(path), func(i int) bool { return path[i] >= threshold })
+	if t == len(path) {
+		return -1
+	}
+	return t
+}
+
+// * ARLEstimate is the Monte Carlo estimate of an average run length.
+type ARLEstimate struct {
+	// ARL is the average number of values up to the alarm, the alarm included
+	ARL    float64
+	StdErr float64
+	// Runs is the number of simulated streams, Alarms of them alarmed in time
+	Runs, Alarms int
+	// Censored is the number of streams without an alarm in their horizon
+	Censored int
+	// FalseAlarms is the number of streams which alarmed before the change, ARL1 only
+	FalseAlarms int
+}
+
+// arl0 estimates the ARL0 from the paths of in-control streams of n values:
+// a stream without an alarm is censored at n, the estimate is the number of
+// values seen over the number of alarms, the MLE of a geometric run length
+func arl0(paths [][]float64, n []int, threshold float64) ARLEstimate {
+	est := ARLEstimate{Runs: len(paths)}
+	steps := 0
+	for i, p := range paths {
+		if t := firstAlarm(p, threshold); t >= 0 {
+			steps += t + 1
+			est.Alarms++
+		} else {
+			steps += n[i]
+			est.Censored++
+		}
+	}
+	est.ARL, est.StdErr = math.Inf(1), math.Inf(1)
+	if est.Alarms > 0 {
+		est.ARL = float64(steps) / float64(est.Alarms)
+		est.StdErr = est.ARL / math.Sqrt(float64(est.Alarms))
+	}
+	return est
+}
+
+// ARL0 estimates the in-control average run length of the monitor at the
+// threshold, over runs streams without changepoints drawn by sim(seed+i).
+// The horizon is the length of the streams, make it a few times the ARL0:
+// the streams without an alarm are censored.
+func ARL0(m Monitor, threshold float64, sim func(seed int64) Simulation, runs int, seed int64) ARLEstimate {
+	paths, n := inControlPaths(m, threshold, sim, runs, seed)
+	return arl0(paths, n, threshold)
+}
+
+func inControlPaths(m Monitor, stop float64, sim func(seed int64) Simulation, runs int, seed int64) ([][]float64, []int) {
+	if runs < 1 {
+		panic("runs must be positive")
+	}
+	paths := make([][]float64, runs)
+	n := make([]int, runs)
+	for i := range paths {
+		s := sim(seed + int64(i))
+		if len(s.Changepoints) > 0 {
+			panic("the in-control streams must not have changepoints")
+		}
+		paths[i], n[i] = m.path(s.Data, stop), len(s.Data)
+	}
+	return paths, n
+}
+
+// ARL1 estimates the detection delay of the monitor at the threshold, over
+// runs streams drawn by sim(seed+i) with the change at their first
+// changepoint. The delay counts the values from the change to the alarm, both
+// included. The streams which alarm before the change are FalseAlarms, the
+// ones without an alarm are Censored, neither counts in the ARL.
+func ARL1(m Monitor, threshold float64, sim func(seed int64) Simulation, runs int, seed int64) ARLEstimate {
+	if runs < 1 {
+		panic("runs must be positive")
+	}
+	est := ARLEstimate{Runs: runs}
+	delays := make([]float64, 0, runs)
+	for i := 0; i < runs; i++ {
+		s := sim(seed + int64(i))
+		if len(s.Changepoints) == 0 {
+			panic("the streams need a changepoint")
+		}
+		change := s.Changepoints[0]
+		t := firstAlarm(m.path(s.Data, threshold), threshold)
+		switch {
+		case t < 0:
+			est.Censored++
+		case t < change:
+			est.FalseAlarms++
+		default:
+			est.Alarms++
+			delays = append(delays, float64(t-change+1))
+		}
+	}
+	est.ARL, est.StdErr = math.NaN(), math.NaN()
+	if len(delays) > 0 {
+		est.ARL = SumSlice(delays) / float64(len(delays))
+		v := 0.0
+		for _, d := range delays {
+			v += (d - est.ARL) * (d - est.ARL)
+		}
+		est.StdErr = math.Sqrt(v/float64(len(delays))) / math.Sqrt(float64(len(delays)))
+	}
+	return est
+}
+
+// CalibrateThreshold returns the smallest threshold in [lo, hi] whose ARL0
+// is at least target, with its estimate. Every threshold is scored on the
+// same streams, which are simulated once: the ARL0 is then monotone in the
+// threshold and the search is a bisection. If hi does not reach the target,
+// it returns hi, whose estimate tells how far it is.
+func CalibrateThreshold(m Monitor, target float64, sim func(seed int64) Simulation, runs int, seed int64, lo, hi float64) (float64, ARLEstimate) {
+	if hi <= lo {
+		panic("the threshold range needs lo < hi")
+	}
+	paths, n := inControlPaths(m, hi, sim, runs, seed)
+	if est := arl0(paths, n, lo); est.ARL >= target {
+		return lo, est
+	}
+	if est := arl0(paths, n, hi); est.ARL < target {
+		return hi, est
+	}
+	// @ keep ARL0(lo) < target <= ARL0(hi)
+	for i := 0; i < 60 && hi-lo > 1e-12*math.Max(1, math.Abs(hi)); i++ {
+		mid := lo + (hi-lo)/2
+		if arl0(paths, n, mid).ARL >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, arl0(paths, n, hi)
+}
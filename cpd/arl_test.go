@@ -0,0 +1,108 @@
+package cpd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// absValue is a Shewhart chart of known ARL: it watches |x| of N(0, 1) data
+type absValue struct {
+	step int
+	x    float64
+}
+
+func (a *absValue) Update(x float64) []Event {
+	a.step++
+	a.x = x
+	return nil
+}
+
+func (a *absValue) State() State {
+	return State{Step: a.step, Statistic: math.Abs(a.x)}
+}
+
+func inControl(n int) func(seed int64) Simulation {
+	return func(seed int64) Simulation {
+		return SimulateMeanShift([]int{n}, []float64{0}, 1, seed)
+	}
+}
+
+func shifted(before, after int, shift float64) func(seed int64) Simulation {
+	return func(seed int64) Simulation {
+		return SimulateMeanShift([]int{before, after}, []float64{0, shift}, 1, seed)
+	}
+}
+
+// test the Monte Carlo ARLs against the closed form 1/P(|Z| >= h)
+func TestARL(t *testing.T) {
+	m := StateMonitor(func() Detector { return &absValue{} })
+
+	// P(|Z| >= 3) = 0.0027, ARL0 = 370.4
+	est := ARL0(m, 3, inControl(2000), 200, 1)
+	assert.Equal(t, 200, est.Runs)
+	assert.InDelta(t, 370.4, est.ARL, 3*est.StdErr)
+	assert.Less(t, est.StdErr, 30.0)
+	assert.Equal(t, est.Runs, est.Alarms+est.Censored)
+
+	// a short horizon censors the runs, the estimate stays unbiased
+	short := ARL0(m, 3, inControl(200), 400, 1)
+	assert.Greater(t, short.Censored, 100)
+	assert.InDelta(t, 370.4, short.ARL, 3*short.StdErr)
+
+	// P(|Z+3| >= 3) = 0.5, ARL1 = 2
+	est = ARL1(m, 3, shifted(20, 100, 3), 500, 1)
+	assert.InDelta(t, 2, est.ARL, 0.2)
+	assert.Greater(t, est.FalseAlarms, 0)
+	assert.Equal(t, est.Runs, est.Alarms+est.FalseAlarms+est.Censored)
+
+	// the calibrated threshold is the quantile of the target
+	h, est := CalibrateThreshold(m, 370.4, inControl(2000), 200, 1, 1, 6)
+	assert.InDelta(t, 3, h, 0.15)
+	assert.GreaterOrEqual(t, est.ARL, 370.4)
+	assert.Less(t, ARL0(m, h-1e-6, inControl(2000), 200, 1).ARL, 370.4)
+
+	// out of the range
+	h, est = CalibrateThreshold(m, 1e6, inControl(100), 10, 1, 1, 2)
+	assert.Equal(t, 2.0, h)
+	assert.Less(t, est.ARL, 1e6)
+	assert.Panics(t, func() { ARL1(m, 3, inControl(10), 1, 1) })
+	assert.Panics(t, func() { ARL0(m, 3, shifted(10, 10, 1), 1, 1) })
+}
+
+// test the OCPD monitor on the probability of a recent change
+func TestOCPDMonitor(t *testing.T) {
+	newOCPD := func() *OCPD {
+		return NewOCPD(100, ConstantHazardSlice, NewStudentT_BU([]float64{1}, []float64{1}, []float64{1}, []float64{0}))
+	}
+	d := newOCPD()
+	d.OCPD_Update(0.5)
+	// the start of the stream is not a change
+	assert.Equal(t, 0.0, d.ChangeProbability(5))
+	for _, x := range SimulateMeanShift([]int{100, 5}, []float64{0, 8}, 1, 1).Data {
+		d.OCPD_Update(x)
+	}
+	// the next value starts a segment with the hazard, whatever the data
+	assert.InDelta(t, 0.01, d.Res[0], 1e-12)
+	assert.Greater(t, d.ChangeProbability(5), 0.8)
+	assert.Less(t, d.ChangeProbability(1), 0.1)
+	assert.InDelta(t, 1-0.01-d.Res[len(d.Res)-1], d.ChangeProbability(len(d.Res)), 1e-9)
+
+	m := OCPDMonitor(newOCPD, 5)
+	low := ARL0(m, 0.3, inControl(500), 20, 1)
+	high := ARL0(m, 0.7, inControl(500), 20, 1)
+	assert.Less(t, low.ARL, high.ARL)
+	delay := ARL1(m, 0.5, shifted(100, 50, 5), 20, 1)
+	assert.Equal(t, 20, delay.Alarms)
+	assert.InDelta(t, 1, delay.ARL, 0.5)
+
+	h, est := CalibrateThreshold(m, 1000, inControl(500), 20, 1, 0.05, 0.99)
+	assert.GreaterOrEqual(t, est.ARL, 1000.0)
+	assert.True(t, h > 0.3 && h < 0.99)
+	assert.Equal(t, est, ARL0(m, h, inControl(500), 20, 1))
+
+	// the control charts never reset with an infinite threshold
+	cusum := StateMonitor(func() Detector { return NewCUSUM(0.5, math.Inf(1), TwoSided, 20) })
+	assert.Greater(t, ARL0(cusum, 8, inControl(1000), 20, 1).ARL, ARL1(cusum, 8, shifted(100, 200, 2), 20, 1).ARL)
+}
@@ -0,0 +1,173 @@
+package cpd
+
+import (
+	"math"
+	"sort"
+)
+
+// * Bayesian Blocks of Scargle et al. (2013): the optimal piecewise constant
+// * segmentation found by dynamic programming over the data cells.
+// * Three kinds of data are supported: the event times, the binned counts
+// * and the measurements with errors. The events are the first data of every
+// * block but the first, and the Segments are the blocks with their edges.
+
+// NCPPriorEvents is the prior on the number of blocks for the event data
+// (eq. 21 of Scargle et al.), p0 is the false alarm probability
+func NCPPriorEvents(p0 float64, n int) float64 {
+	return 4 - math.Log(73.53*p0*math.Pow(float64(n), -0.478))
+}
+
+// NCPPriorMeasures is the prior on the number of blocks for the measurements
+// (eq. 31 of Scargle et al.), calibrated for the false alarm probability 0.05
+func NCPPriorMeasures(n int) float64 {
+	return 1.32 + 0.577*math.Log10(float64(n))
+}
+
+// * BayesianBlocksEvents segments the event times into blocks of constant rate.
+// * The times are sorted first, the equal times make one cell together.
+// * ncpPrior <= 0 means NCPPriorEvents with p0 = 0.05.
+// * The Value of a segment is its rate, events per unit of time.
+func BayesianBlocksEvents(times []float64, ncpPrior float64) Result {
+	sorted := append([]float64{}, times...)
+	sort.Float64s(sorted)
+	// @ the cells are the unique times, the edges are the midpoints
+	var cellTimes, counts []float64
+	var cellStart []int
+	for i, t := range sorted {
+		if len(cellTimes) > 0 && t == cellTimes[len(cellTimes)-1] {
+			counts[len(counts)-1]++
+			continue
+		}
+		cellTimes = append(cellTimes, t)
+		counts = append(counts, 1)
+		cellStart = append(cellStart, i)
+	}
+	if ncpPrior <= 0 {
+		ncpPrior = NCPPriorEvents(0.05, len(sorted))
+	}
+	edges := midpointEdges(cellTimes)
+	return bayesianBlocks("BayesianBlocksEvents", len(sorted), edges, cellStart, ncpPrior, countFitness(counts, edges))
+}
+
+// * BayesianBlocksBinned segments the counts of the regular bins of width
+// * binWidth, bin i covers [i*binWidth, (i+1)*binWidth).
+// * ncpPrior <= 0 means NCPPriorEvents with p0 = 0.05 and the total count.
+// * The Value of a segment is its rate, counts per unit of time.
+func BayesianBlocksBinned(counts []float64, binWidth float64, ncpPrior float64) Result {
+	edges := make([]float64, len(counts)+1)
+	cellStart := make([]int, len(counts))
+	for i := range edges {
+		edges[i] = float64(i) * binWidth
+	}
+	for i := range cellStart {
+		cellStart[i] = i
+	}
+	if ncpPrior <= 0 {
+		ncpPrior = NCPPriorEvents(0.05, int(math.Max(1, SumSlice(counts))))
+	}
+	return bayesianBlocks("BayesianBlocksBinned", len(counts), edges, cellStart, ncpPrior, countFitness(counts, edges))
+}
+
+// * BayesianBlocksMeasures segments the measurements x at the sorted times t
+// * with the normal errors sigma. ncpPrior <= 0 means NCPPriorMeasures.
+// * The Value of a segment is the weighted mean of its measurements.
+func BayesianBlocksMeasures(t, x, sigma []float64, ncpPrior float64) Result {
+	if len(t) != len(x) || len(t) != len(sigma) {
+		panic("Parameters t, x and sigma must have the same length")
+	}
+	n := len(x)
+	// @ a = 1/(2 sigma^2), b = -x/sigma^2, the fitness of a block is (sum b)^2 / (4 sum a)
+	a := make([]float64, n+1)
+	b := make([]float64, n+1)
+	cellStart := make([]int, n)
+	for i := range x {
+		a[i+1] = a[i] + 1/(2*sigma[i]*sigma[i])
+		b[i+1] = b[i] - x[i]/(sigma[i]*sigma[i])
+		cellStart[i] = i
+	}
+	if ncpPrior <= 0 {
+		ncpPrior = NCPPriorMeasures(n)
+	}
+	fitness := func(r, R int) (float64, float64) {
+		sa, sb := a[R+1]-a[r], b[R+1]-b[r]
+		return sb * sb / (4 * sa), -sb / (2 * sa)
+	}
+	return bayesianBlocks("BayesianBlocksMeasures", n, midpointEdges(t), cellStart, ncpPrior, fitness)
+}
+
+// midpointEdges returns the cell edges: the first and the last time,
+// and the midpoints between the consecutive times
+func midpointEdges(t []float64) []float64 {
+	n := len(t)
+	if n == 0 {
+		return []float64{0}
+	}
+	edges := make([]float64, n+1)
+	edges[0], edges[n] = t[0], t[n-1]
+	for i := 1; i < n; i++ {
+		edges[i] = (t[i-1] + t[i]) / 2
+	}
+	return edges
+}
+
+// countFitness returns the fitness N (log N - log T) of the cells r..R, and the rate N/T
+func countFitness(counts, edges []float64) func(r, R int) (float64, float64) {
+	cum := make([]float64, len(counts)+1)
+	for i, c := range counts {
+		cum[i+1] = cum[i] + c
+	}
+	return func(r, R int) (float64, float64) {
+		n := cum[R+1] - cum[r]
+		width := edges[R+1] - edges[r]
+		if n <= 0 || width <= 0 {
+			return 0, 0
+		}
+		return n * (math.Log(n) - math.Log(width)), n / width
+	}
+}
+
+// bayesianBlocks is the dynamic programming of the Bayesian Blocks:
+// best[R] = max over r of best[r-1] + fitness(r, R) - ncpPrior.
+// cellStart maps a cell to its first datum of the n data.
+func bayesianBlocks(method string, n int, edges []float64, cellStart []int, ncpPrior float64, fitness func(r, R int) (float64, float64)) Result {
+	cells := len(cellStart)
+	if cells == 0 {
+		return NewOfflineResult(method, n, nil, nil)
+	}
+	best := make([]float64, cells)
+	last := make([]int, cells)
+	for R := 0; R < cells; R++ {
+		best[R] = math.Inf(-1)
+		for r := 0; r <= R; r++ {
+			f, _ := fitness(r, R)
+			v := f - ncpPrior
+			if r > 0 {
+				v += best[r-1]
+			}
+			if v > best[R] {
+				best[R], last[R] = v, r
+			}
+		}
+	}
+	// @ backtrack the first cells of the blocks
+	var starts []int
+	for R := cells - 1; R >= 0; R = last[R] - 1 {
+		starts = append([]int{last[R]}, starts...)
+	}
+	cps := make([]int, 0, len(starts)-1)
+	segments := make([]Segment, len(starts))
+	for i, r := range starts {
+		R := cells - 1
+		end := n
+		if i+1 < len(starts) {
+			R = starts[i+1] - 1
+			end = cellStart[starts[i+1]]
+			cps = append(cps, end)
+		}
+		_, value := fitness(r, R)
+		segments[i] = Segment{Start: cellStart[r], End: end, From: edges[r], To: edges[R+1], Value: value}
+	}
+	res := NewOfflineResult(method, n, cps, nil)
+	res.Segments = segments
+	return res
+}
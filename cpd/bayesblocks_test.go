@@ -0,0 +1,68 @@
+package cpd
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// piecewiseEvents draws the arrivals of a Poisson process with the rates on [0, 100), [100, 150), [150, 250)
+func piecewiseEvents(seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	edges := []float64{0, 100, 150, 250}
+	rates := []float64{1, 5, 1}
+	var times []float64
+	for i, rate := range rates {
+		for t := edges[i] + rng.ExpFloat64()/rate; t < edges[i+1]; t += rng.ExpFloat64() / rate {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// test the blocks of the event data follow the rate of the process
+func TestBayesianBlocksEvents(t *testing.T) {
+	times := piecewiseEvents(1)
+	// shuffle the times, the detector sorts them
+	rand.New(rand.NewSource(2)).Shuffle(len(times), func(i, j int) { times[i], times[j] = times[j], times[i] })
+	res := BayesianBlocksEvents(times, 0)
+	assert.Equal(t, 3, len(res.Segments))
+	assert.Equal(t, len(times), res.N)
+	assert.InDelta(t, 100, res.Segments[0].To, 3)
+	assert.InDelta(t, 150, res.Segments[1].To, 3)
+	assert.InDelta(t, 1, res.Segments[0].Value, 0.3)
+	assert.InDelta(t, 5, res.Segments[1].Value, 1)
+	// the segments cover the data and start at the changepoints
+	assert.Equal(t, 0, res.Segments[0].Start)
+	assert.Equal(t, len(times), res.Segments[2].End)
+	assert.Equal(t, []int{res.Segments[1].Start, res.Segments[2].Start}, res.Changepoints())
+}
+
+// test the binned counts of the same process
+func TestBayesianBlocksBinned(t *testing.T) {
+	counts := make([]float64, 50)
+	for _, x := range piecewiseEvents(3) {
+		counts[int(x/5)]++
+	}
+	res := BayesianBlocksBinned(counts, 5, 0)
+	assert.Equal(t, []int{20, 30}, res.Changepoints())
+	assert.InDelta(t, 5, res.Segments[1].Value, 1)
+}
+
+// test the measurements of ../data/data_output.csv
+func TestBayesianBlocksMeasures(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	times := make([]float64, len(data))
+	sigma := make([]float64, len(data))
+	for i := range data {
+		times[i] = float64(i)
+		sigma[i] = 2.5
+	}
+	res := BayesianBlocksMeasures(times, data, sigma, 0)
+	// the last segment is the noisiest one, only the first changepoints are sure
+	assert.Equal(t, []int{58, 132, 249, 402, 539, 668}, res.Changepoints()[:6])
+	assert.InDelta(t, 9.75, res.Segments[0].Value, 0.01)
+	assert.InDelta(t, -16.58, res.Segments[1].Value, 0.01)
+	assert.Equal(t, 57.5, res.Segments[0].To)
+}
@@ -0,0 +1,110 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+)
+
+// * CUSUMStatistic returns the best split of data[start:end] and its CUSUM
+// * statistic sqrt((b-s)(e-b)/(e-s)) * |mean(s:b) - mean(b:e)| / sigma.
+// * s1 is the cumulative sum of the data with a leading 0.
+// * It returns -1 for the split if the segment is too short.
+func CUSUMStatistic(s1 []float64, start, end, minSize int, sigma float64) (int, float64) {
+	best, bestStat := -1, 0.0
+	n := float64(end - start)
+	total := s1[end] - s1[start]
+	for b := start + minSize; b <= end-minSize; b++ {
+		nl := float64(b - start)
+		nr := n - nl
+		left := s1[b] - s1[start]
+		stat := math.Sqrt(nl*nr/n) * math.Abs(left/nl-(total-left)/nr) / sigma
+		if best < 0 || stat > bestStat {
+			best, bestStat = b, stat
+		}
+	}
+	return best, bestStat
+}
+
+// ThresholdFromPenalty turns a penalty on the cost reduction into a CUSUM
+// threshold: the squared CUSUM statistic is the reduction of the mean cost.
+func ThresholdFromPenalty(penalty Penalty, n int) float64 {
+	return math.Sqrt(penalty.value(n, 1))
+}
+
+// * BinarySegmentation splits the segment with the largest CUSUM statistic
+// * again and again, until maxCps changepoints (<= 0 means no limit) are
+// * found or no statistic reaches the threshold (in noise sigma units).
+func BinarySegmentation(data []float64, maxCps int, threshold float64, minSize int) Result {
+	return greedySegmentation("BinarySegmentation", data, maxCps, threshold, minSize, nil)
+}
+
+// * WildBinarySegmentation is the WBS of Fryzlewicz (2014): the CUSUM statistic
+// * of a segment is the maximum over the intervals random drawn inside it,
+// * which finds the short segments hidden between two long ones.
+// * intervals is the number of random intervals drawn with the seed.
+func WildBinarySegmentation(data []float64, maxCps int, threshold float64, minSize, intervals int, seed int64) Result {
+	rng := rand.New(rand.NewSource(seed))
+	n := len(data)
+	draws := make([][2]int, 0, intervals)
+	for len(draws) < intervals && n > 2*minSize {
+		s := rng.Intn(n)
+		e := rng.Intn(n + 1)
+		if s > e {
+			s, e = e, s
+		}
+		if e-s >= 2*minSize {
+			draws = append(draws, [2]int{s, e})
+		}
+	}
+	return greedySegmentation("WildBinarySegmentation", data, maxCps, threshold, minSize, draws)
+}
+
+// greedySegmentation is the common part of the binary segmentation detectors.
+// With draws the statistic of a segment is also searched in the draws inside it.
+func greedySegmentation(method string, data []float64, maxCps int, threshold float64, minSize int, draws [][2]int) Result {
+	n := len(data)
+	if minSize < 1 {
+		minSize = 1
+	}
+	s1, _ := cumSums(data)
+	sigma := math.Sqrt(madDiffVariance(data))
+
+	type split struct {
+		start, end, cp int
+		stat           float64
+	}
+	bestSplit := func(start, end int) split {
+		cp, stat := CUSUMStatistic(s1, start, end, minSize, sigma)
+		for _, d := range draws {
+			if d[0] < start || d[1] > end {
+				continue
+			}
+			if c, s := CUSUMStatistic(s1, d[0], d[1], minSize, sigma); c >= 0 && s > stat {
+				cp, stat = c, s
+			}
+		}
+		return split{start, end, cp, stat}
+	}
+
+	var cps []int
+	var scores []float64
+	segments := []split{bestSplit(0, n)}
+	for maxCps <= 0 || len(cps) < maxCps {
+		// @ pick the segment with the largest statistic
+		idx := -1
+		for i, s := range segments {
+			if s.cp >= 0 && (idx < 0 || s.stat > segments[idx].stat) {
+				idx = i
+			}
+		}
+		if idx < 0 || segments[idx].stat < threshold {
+			break
+		}
+		s := segments[idx]
+		cps = append(cps, s.cp)
+		scores = append(scores, s.stat)
+		segments[idx] = bestSplit(s.start, s.cp)
+		segments = append(segments, bestSplit(s.cp, s.end))
+	}
+	return NewOfflineResult(method, n, cps, scores)
+}
@@ -0,0 +1,42 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the binary segmentation detectors against the partition of ../data/data_output.csv
+func TestBinarySegmentation(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+	threshold := ThresholdFromPenalty(Penalty{Kind: MBIC}, len(data))
+
+	res := BinarySegmentation(data, 6, threshold, 5)
+	assert.Equal(t, "BinarySegmentation", res.Method)
+	assert.Equal(t, truth, res.Changepoints())
+
+	// the max number of changepoints stops the search first
+	res = BinarySegmentation(data, 3, threshold, 5)
+	assert.Equal(t, 3, len(res.Changepoints()))
+
+	res = WildBinarySegmentation(data, 6, threshold, 5, 500, 1)
+	assert.Equal(t, "WildBinarySegmentation", res.Method)
+	assert.Equal(t, truth, res.Changepoints())
+	// the same seed gives the same result
+	assert.Equal(t, res, WildBinarySegmentation(data, 6, threshold, 5, 500, 1))
+}
+
+// test WBS finds the short segment that binary segmentation misses
+func TestWildBinarySegmentationShortSegment(t *testing.T) {
+	data := make([]float64, 600)
+	for i := range data {
+		// a tiny deterministic noise and a short bump in the middle
+		data[i] = 0.3 * float64((i*7)%5-2)
+		if i >= 300 && i < 315 {
+			data[i] += 3
+		}
+	}
+	res := WildBinarySegmentation(data, 0, 3, 2, 1000, 7)
+	assert.Equal(t, []int{300, 315}, res.Changepoints())
+}
@@ -45,43 +45,85 @@ func ConstantHazardSlice(lam float64, r []float64) []float64 {
 }
 
 // * Define the StudentT_Bayesian_Update struct
+// * With K prior hypotheses the parameters are laid out run length by run length:
+// * the entry r*K+k belongs to run length r and prior k.
+// * weight keeps the posterior weight of each prior for each run length.
 type StudentT_Bayesian_Update struct {
-	alpha, beta, kappa, mu     []float64
-	alpha0, beta0, kappa0, mu0 []float64
+	alpha, beta, kappa, mu, weight      []float64
+	alpha0, beta0, kappa0, mu0, weight0 []float64
 }
 
 // * NewStudentT_BU creates a new StudentT_Bayesian_Update struct with the given parameters.
 // * Use copy to avoid interference between the two parameter sets.
+// * Multiple entries are the mixture of prior hypotheses with equal weights.
 func NewStudentT_BU(t_alpha, t_beta, t_kappa, t_mu []float64) *StudentT_Bayesian_Update {
+	weights := make([]float64, len(t_alpha))
+	for i := range weights {
+		weights[i] = 1.0
+	}
+	return NewStudentT_BUMixture(t_alpha, t_beta, t_kappa, t_mu, weights)
+}
+
+// * NewStudentT_BUMixture creates a StudentT_Bayesian_Update struct with K weighted priors,
+// * e.g. a "small noise" and a "large noise" hypothesis for the new segment.
+// * The weights will be normalized.
+func NewStudentT_BUMixture(t_alpha, t_beta, t_kappa, t_mu, t_weight []float64) *StudentT_Bayesian_Update {
+	k := len(t_alpha)
+	if k == 0 || k != len(t_beta) || k != len(t_kappa) || k != len(t_mu) || k != len(t_weight) {
+		panic("Parameters alpha, beta, kappa, mu and weight must be non-empty and have the same length")
+	}
 	st := &StudentT_Bayesian_Update{
-		alpha:  make([]float64, len(t_alpha)),
-		beta:   make([]float64, len(t_beta)),
-		kappa:  make([]float64, len(t_kappa)),
-		mu:     make([]float64, len(t_mu)),
-		alpha0: make([]float64, len(t_alpha)),
-		beta0:  make([]float64, len(t_beta)),
-		kappa0: make([]float64, len(t_kappa)),
-		mu0:    make([]float64, len(t_mu)),
+		alpha:   make([]float64, k),
+		beta:    make([]float64, k),
+		kappa:   make([]float64, k),
+		mu:      make([]float64, k),
+		weight:  make([]float64, k),
+		alpha0:  make([]float64, k),
+		beta0:   make([]float64, k),
+		kappa0:  make([]float64, k),
+		mu0:     make([]float64, k),
+		weight0: make([]float64, k),
 	}
 	copy(st.alpha, t_alpha)
 	copy(st.beta, t_beta)
 	copy(st.kappa, t_kappa)
 	copy(st.mu, t_mu)
+	copy(st.weight, t_weight)
 	copy(st.alpha0, t_alpha)
 	copy(st.beta0, t_beta)
 	copy(st.kappa0, t_kappa)
 	copy(st.mu0, t_mu)
+	copy(st.weight0, t_weight)
+	NormalizeSlice(st.weight)
+	NormalizeSlice(st.weight0)
 	return st
 }
 
 // * Method: PDF computes the probability density function
 // *   of the Student's t-distribution for the given data.
 // *  the output is a 2D slice
-// * the first dimension corresponding to the parameters set slice, for alpha, beta, kappa, mu
+// * the first dimension corresponding to the run length, the priors are marginalised out
 // * the second dimension corresponding to the data slice，most of the time just one element
 func (st *StudentT_Bayesian_Update) PDF(data []float64) [][]float64 {
+	k := len(st.alpha0)
+	comp := st.componentPDF(data)
+	res := make([][]float64, 0)
+	for r := 0; r < len(st.alpha)/k; r++ {
+		pdfs := make([]float64, len(data))
+		for i := r * k; i < (r+1)*k; i++ {
+			for j := range data {
+				pdfs[j] += st.weight[i] * comp[i][j]
+			}
+		}
+		res = append(res, pdfs)
+	}
+	return res
+}
+
+// componentPDF computes the density of the data under every (run length, prior) entry
+func (st *StudentT_Bayesian_Update) componentPDF(data []float64) [][]float64 {
 	// check all the parameters are the same length
-	if len(st.alpha) != len(st.beta) || len(st.alpha) != len(st.kappa) || len(st.alpha) != len(st.mu) {
+	if len(st.alpha) != len(st.beta) || len(st.alpha) != len(st.kappa) || len(st.alpha) != len(st.mu) || len(st.alpha) != len(st.weight) {
 		panic("Parameters alpha, beta, kappa, and mu must have the same length")
 	}
 	res := make([][]float64, 0)
@@ -97,24 +139,81 @@ func (st *StudentT_Bayesian_Update) PDF(data []float64) [][]float64 {
 	return res
 }
 
+// * Method: PriorWeights returns the posterior weights of the K priors for run length r
+func (st *StudentT_Bayesian_Update) PriorWeights(r int) []float64 {
+	k := len(st.alpha0)
+	res := make([]float64, k)
+	copy(res, st.weight[r*k:(r+1)*k])
+	return res
+}
+
+// * Method: Mean returns the posterior mean of mu for run length r, over the priors
+func (st *StudentT_Bayesian_Update) Mean(r int) float64 {
+	k := len(st.alpha0)
+	return SumSlice(MulSlice(st.weight[r*k:(r+1)*k], st.mu[r*k:(r+1)*k]))
+}
+
+// * Method: Params returns mu, kappa, alpha, beta and the weight of each prior for run length r
+func (st *StudentT_Bayesian_Update) Params(r int) []float64 {
+	k := len(st.alpha0)
+	res := make([]float64, 0, 5*k)
+	for i := r * k; i < (r+1)*k; i++ {
+		res = append(res, st.mu[i], st.kappa[i], st.alpha[i], st.beta[i], st.weight[i])
+	}
+	return res
+}
+
+// * Method: Keep keeps the parameter sets of the given run length indices, in that order
+func (st *StudentT_Bayesian_Update) Keep(indices []int) {
+	k := len(st.alpha0)
+	pick := func(v []float64) []float64 {
+		res := make([]float64, 0, len(indices)*k)
+		for _, r := range indices {
+			res = append(res, v[r*k:(r+1)*k]...)
+		}
+		return res
+	}
+	st.alpha, st.beta, st.kappa, st.mu, st.weight = pick(st.alpha), pick(st.beta), pick(st.kappa), pick(st.mu), pick(st.weight)
+}
+
 // * Method: UpdateTheta updates the parameters of the Student's t-distribution
+// * The data are taken one after the other, each one is a new time step.
 func (st *StudentT_Bayesian_Update) UpdateTheta(data []float64) {
+	for _, x := range data {
+		st.updateOne(x)
+	}
+}
+
+func (st *StudentT_Bayesian_Update) updateOne(x float64) {
 	// @ 1. check all the parameters are the same length
 	par_len := len(st.alpha)
 	if par_len != len(st.beta) || par_len != len(st.kappa) || par_len != len(st.mu) {
 		panic("Parameters alpha, beta, kappa, and mu must have the same length")
 	}
-	// @ 2. copy the data to avoid interference
-	tmpdata := make([]float64, len(data))
-	copy(tmpdata, data)
+	// @ 2. update the prior weights with the density of x, before the parameters change
+	// @    w[r+1, k] = w[r, k] * p(x | r, k) / sum_k w[r, k] * p(x | r, k)
+	k := len(st.alpha0)
+	comp := st.componentPDF([]float64{x})
+	weightT0 := make([]float64, k, par_len+k)
+	copy(weightT0, st.weight0)
+	for r := 0; r < par_len/k; r++ {
+		tmp := make([]float64, k)
+		for i := range tmp {
+			tmp[i] = st.weight[r*k+i] * comp[r*k+i][0]
+		}
+		if SumSlice(tmp) > 0 {
+			NormalizeSlice(tmp)
+		} else {
+			copy(tmp, st.weight[r*k:(r+1)*k])
+		}
+		weightT0 = append(weightT0, tmp...)
+	}
 
-	// @ 3. make the data length the same as the parameters
+	// @ 3. broadcast x to the same length as the parameters
 	// @    to use the mat.VecDense operation
-	if len(tmpdata) < len(st.alpha) {
-		lastElement := tmpdata[len(tmpdata)-1]
-		for len(tmpdata) < len(st.alpha) {
-			tmpdata = append(tmpdata, lastElement)
-		}
+	tmpdata := make([]float64, par_len)
+	for i := range tmpdata {
+		tmpdata[i] = x
 	}
 	// @ 4. change data into mat.vecDense format
 	// @  change data into mat.vecDense format with the same length as the parameters
@@ -167,6 +266,7 @@ func (st *StudentT_Bayesian_Update) UpdateTheta(data []float64) {
 	st.kappa = TransformVecDenseToSlice(kappaT0)
 	st.alpha = TransformVecDenseToSlice(alphaT0)
 	st.beta = TransformVecDenseToSlice(betaT0)
+	st.weight = weightT0
 
 }
 
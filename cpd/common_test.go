@@ -70,3 +70,39 @@ func TestPDF(t *testing.T) {
 
 }
 
+
+// test the mixture of priors picks the prior of the current segment
+func TestStudentTMixture(t *testing.T) {
+	// one prior is the same as the original struct
+	single := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	mixture := NewStudentT_BUMixture([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0}, []float64{3})
+	single.UpdateTheta([]float64{1.5, 2.5})
+	mixture.UpdateTheta([]float64{1.5, 2.5})
+	assert.Equal(t, single.PDF([]float64{2}), mixture.PDF([]float64{2}))
+	assert.Equal(t, 3, len(single.PDF([]float64{2})))
+
+	// "small noise" and "large noise" priors, the same number of entries per run length
+	st := NewStudentT_BUMixture(
+		[]float64{10, 10},
+		[]float64{0.1, 100},
+		[]float64{0.01, 0.01},
+		[]float64{0, 0},
+		[]float64{0.5, 0.5},
+	)
+	cpd := NewOCPD(250, ConstantHazardSlice, st)
+	data := []float64{0.1, -0.05, 0.08, 0.02, -0.1, 0.03, -0.02, 0.06}
+	for _, x := range data {
+		cpd.OCPD_Update(x)
+	}
+	assert.Equal(t, len(data)+1, len(st.PDF([]float64{0})))
+	w := cpd.PriorWeights()
+	assert.InDelta(t, 1.0, SumSlice(w), 1e-9)
+	assert.Greater(t, w[0], 0.99)
+
+	for _, x := range []float64{12, -9, 15, -11, 8, -14, 10, -7} {
+		cpd.OCPD_Update(x)
+	}
+	assert.Greater(t, cpd.PriorWeights()[1], 0.99)
+	// the favoured prior of the long run lengths is kept per run length
+	assert.Greater(t, st.PriorWeights(len(data)+8)[1], 0.5)
+}
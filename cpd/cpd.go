@@ -1,11 +1,8 @@
 package cpd
 
 import (
-	"encoding/csv"
-	"log"
 	"math"
-	"os"
-	"strconv"
+	"time"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -14,7 +11,7 @@ func OnlineChangepointDetection(
 	data []float64,
 	lam float64,
 	hazardFunction func(float64, *mat.Dense) *mat.Dense,
-	logLikelihoodClass *StudentT_Bayesian_Update) (mat.Dense, []float64) {
+	logLikelihoodClass ObservationModel) (mat.Dense, []float64) {
 
 	// Parameters:
 	// data    -- the time series data
@@ -84,25 +81,83 @@ func OnlineChangepointDetection(
 type OCPD struct {
 	lam            float64
 	hazardFunction func(float64, []float64) []float64
-	st             *StudentT_Bayesian_Update
+	st             ObservationModel
+	conf           *confirmation
+	lagged         *fixedLag
+	clock          clock
 	// result part
-	Res   []float64
-	Maxes []float64
+	Res    []float64
+	Maxes  []float64
+	Events []Event
+	// Lagged is the fixed lag changepoint probability of each step, see SetFixedLag
+	Lagged []float64
+	// Times are the timestamps of the steps fed by UpdateAt, aligned with Maxes
+	Times []time.Time
 }
 
 // NewOCPD returns a new CPD_slim
-func NewOCPD(lam float64, hazardFunction func(float64, []float64) []float64, st *StudentT_Bayesian_Update) *OCPD {
+func NewOCPD(lam float64, hazardFunction func(float64, []float64) []float64, st ObservationModel) *OCPD {
 	return &OCPD{
 		lam:            lam,
 		hazardFunction: hazardFunction,
 		st:             st,
 
 		// Res is a slice and initial value is 1.0 for the first element
-		Res:   []float64{1.0},
-		Maxes: make([]float64, 0),
+		Res:    []float64{1.0},
+		Maxes:  make([]float64, 0),
+		Events: make([]Event, 0),
 	}
 }
 
+// SetConfirmation sets the confirmation window for the event layer.
+// After a candidate changepoint the detector waits window steps, then emits
+// a Changepoint if the posterior mass on the short run lengths is at least
+// threshold, or an Outlier if the mass went back to the old segment.
+// Without it or SetFixedLag the detector emits no events, so that a long
+// stream does not pile them up in Events.
+func (cpd *OCPD) SetConfirmation(window int, threshold float64) *OCPD {
+	if window < 0 || threshold < 0 || threshold > 1 {
+		panic("window must be non-negative and threshold must be in [0, 1]")
+	}
+	cpd.conf = &confirmation{window: window, threshold: threshold}
+	return cpd
+}
+
+// SetFixedLag switches the event layer to the fixed lag mode. After each
+// update the detector reports in Lagged the probability that the current
+// segment started lag to lag+width steps ago, given the data up to now, and
+// emits a Changepoint when it is at least threshold. The decision is delayed
+// by lag steps, which filters the transient spikes of P(r=0).
+func (cpd *OCPD) SetFixedLag(lag, width int, threshold float64) *OCPD {
+	if lag < 1 || width < 0 || threshold < 0 || threshold > 1 {
+		panic("lag must be positive, width non-negative and threshold in [0, 1]")
+	}
+	cpd.lagged = &fixedLag{lag: lag, width: width, threshold: threshold, last: -1}
+	cpd.Lagged = make([]float64, 0)
+	return cpd
+}
+
+// SetTimeScale makes lam the expected segment length in units of time, for
+// the irregular samples. UpdateAt then scales the hazard of each step by the
+// units elapsed since the previous sample, 1-(1-H)^dt: a long gap is more
+// likely to hold a change, two samples at the same time never split.
+func (cpd *OCPD) SetTimeScale(unit time.Duration) *OCPD {
+	cpd.clock.setUnit(unit)
+	return cpd
+}
+
+// UpdateAt feeds a value with its timestamp, it makes OCPD a TimedDetector.
+// The events carry the wall clock times and Times the timestamps, so feed
+// a stream either by UpdateAt or by OCPD_Update, not both.
+func (cpd *OCPD) UpdateAt(t time.Time, x float64) []Event {
+	cpd.Times = cpd.clock.tick(cpd.Times, t)
+	n := len(cpd.Events)
+	cpd.OCPD_Update(x)
+	cpd.clock.done()
+	StampEvents(cpd.Events[n:], cpd.Times)
+	return append([]Event{}, cpd.Events[n:]...)
+}
+
 // OnlineChangepointDetectionSlim is a slim version of true online data workflow
 func (cpd *OCPD) OCPD_Update(data float64) {
 	// @ 1. Evaluate the predictive distribution for the new datum under each of
@@ -112,6 +167,8 @@ func (cpd *OCPD) OCPD_Update(data float64) {
 	predprobsvec := GetSliceFrom2dInnerSlice(predprobs, 0)
 	// @ 2. Evaluate the hazard function for this interval
 	H := cpd.hazardFunction(cpd.lam, predprobsvec)
+	// @    over the elapsed time of UpdateAt, see SetTimeScale
+	H = cpd.clock.hazard(H)
 	// @ 3. Evaluate the growth probabilities
 	// @ R[1 : t + 2, t + 1] = R[0 : t + 1, t] * predprobs * (1 - H)
 	tmpR := MulSlice(MulSlice(cpd.Res, predprobsvec),AddConstantSlice(MulConstantSlice(H, -1), 1))
@@ -128,7 +185,50 @@ func (cpd *OCPD) OCPD_Update(data float64) {
 	// @ 6. Update the parameter set for Distribution
 	cpd.st.UpdateTheta([]float64{data})
 	// @ 7. Store the maximum value of the growth probabilities
+	prevMax := -1
+	if len(cpd.Maxes) > 0 {
+		prevMax = int(cpd.Maxes[len(cpd.Maxes)-1])
+	}
 	cpd.Maxes = append(cpd.Maxes, float64(ArgmaxSlice(cpd.Res)))
+	// @ 8. Emit the events whose confirmation window is over, or the fixed lag ones
+	t := len(cpd.Maxes) - 1
+	if cpd.lagged != nil {
+		p, events := cpd.lagged.observe(t, cpd.Res)
+		cpd.Lagged = append(cpd.Lagged, p)
+		cpd.Events = append(cpd.Events, events...)
+		return
+	}
+	if cpd.conf != nil {
+		cpd.Events = append(cpd.Events, cpd.conf.observe(t, cpd.Res, prevMax)...)
+	}
+}
+
+// PriorWeights returns the posterior weights of the mixture priors for the
+// current segment, marginalised over the run lengths. It returns nil when the
+// observation model is not a StudentT_Bayesian_Update.
+func (cpd *OCPD) PriorWeights() []float64 {
+	st, ok := cpd.st.(*StudentT_Bayesian_Update)
+	if !ok {
+		return nil
+	}
+	res := make([]float64, len(st.alpha0))
+	for r, p := range cpd.Res {
+		res = AddSlice(res, MulConstantSlice(st.PriorWeights(r), p))
+	}
+	return res
+}
+
+// ChangeProbability returns the posterior probability that the current
+// segment started within the last k values, the mass of the run lengths 1..k
+// but the one of the whole stream, whose start is not a change.
+// Res[0] is the hazard of the next value, with a constant hazard it is 1/lam
+// whatever the data, so the textbook P(r_t=0) is ChangeProbability(1).
+func (cpd *OCPD) ChangeProbability(k int) float64 {
+	p := 0.0
+	for r := 1; r <= k && r < len(cpd.Res)-1; r++ {
+		p += cpd.Res[r]
+	}
+	return p
 }
 
 func GetVectorFrom2dInnerSlice(slice [][]float64, inner int) *mat.VecDense {
@@ -171,25 +271,21 @@ func ArgmaxVecDense(vec *mat.VecDense) int {
 	return maxIdx
 }
 
-// func to readData from the file
-func ReadData(filename string) []float64 {
-	file, err := os.Open(filename)
+// func to readData from the file: the first column of a csv without header,
+// see ReadCSV for the other layouts and the errors
+func ReadData(filename string) ([]float64, error) {
+	series, err := ReadCSV(filename, CSVOptions{Indices: []int{0}})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	lines, err := reader.ReadAll()
+	return series[0].Data, nil
+}
+
+// MustReadData is ReadData for the files known to be good, it panics on an error
+func MustReadData(filename string) []float64 {
+	data, err := ReadData(filename)
 	if err != nil {
-		log.Fatal(err)
-	}
-	var data []float64
-	for _, line := range lines {
-		val, err := strconv.ParseFloat(line[0], 64)
-		if err != nil {
-			log.Fatal(err)
-		}
-		data = append(data, val)
+		panic(err)
 	}
 	return data
 }
@@ -13,7 +13,7 @@ func TestOnlineChangepointDetection(t *testing.T) {
 	// the data is generated from partition 
 	// [58, 74, 117, 153, 137, 129, 188]
 	// so, the change point is 59, 75, 118, 154, 138, 130, 189
-	data := ReadData("../data/data_output.csv")
+	data := MustReadData("../data/data_output.csv")
 
 	// initialize the parameters part
 	t_alpha := []float64{0.1}
@@ -41,7 +41,7 @@ func TestOCPD(t *testing.T) {
 	// the data is generated from partition 
 	// [58, 74, 117, 153, 137, 129, 188]
 	// so, the change point is 59, 75, 118, 154, 138, 130, 189
-	data := ReadData("../data/data_output.csv")
+	data := MustReadData("../data/data_output.csv")
 	// initialize the parameters part
 	t_alpha := []float64{0.1}
 	t_beta := []float64{0.01}
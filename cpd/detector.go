@@ -0,0 +1,72 @@
+package cpd
+
+import "gonum.org/v1/gonum/mat"
+
+// * Detector is the common interface of the streaming detectors,
+// * so the pipeline code does not care which algorithm is behind it.
+type Detector interface {
+	// Update feeds one value and returns the events decided at this step
+	Update(x float64) []Event
+	// State returns the current state of the detector
+	State() State
+}
+
+// * State is a snapshot of a streaming detector after the last update.
+type State struct {
+	// Step is the number of values seen so far
+	Step int
+	// RunLength is the length of the current segment
+	RunLength int
+	// Statistic is the detector specific decision statistic
+	Statistic float64
+	// Mean and Variance are the reference estimate of the current segment
+	Mean, Variance float64
+}
+
+// Update makes OCPD a Detector
+func (cpd *OCPD) Update(x float64) []Event {
+	n := len(cpd.Events)
+	cpd.OCPD_Update(x)
+	return append([]Event{}, cpd.Events[n:]...)
+}
+
+// State returns the argmax run length and its posterior probability
+func (cpd *OCPD) State() State {
+	s := State{Step: len(cpd.Maxes)}
+	if s.Step > 0 {
+		s.RunLength = int(cpd.Maxes[s.Step-1])
+		s.Statistic = cpd.Res[s.RunLength]
+	}
+	return s
+}
+
+// Result returns the events of OCPD as the common result type
+func (cpd *OCPD) Result() Result {
+	return Result{Method: "OCPD", N: len(cpd.Maxes), Events: append([]Event{}, cpd.Events...)}
+}
+
+// * ResultFromR replays the event layer of OCPD over the R matrix
+// * of OnlineChangepointDetection, so both give the same Result.
+// * Column t+1 of R is the run length posterior after data[t].
+func ResultFromR(R *mat.Dense, window int, threshold float64) Result {
+	rows, _ := R.Dims()
+	n := rows - 1
+	conf := &confirmation{window: window, threshold: threshold}
+	res := Result{Method: "OnlineChangepointDetection", N: n, Events: make([]Event, 0)}
+	prevMax := -1
+	for t := 0; t < n; t++ {
+		col := TransformVecDenseToSlice(GetColVector(R, t+1, 0, t+2))
+		res.Events = append(res.Events, conf.observe(t, col, prevMax)...)
+		prevMax = ArgmaxSlice(col)
+	}
+	return res
+}
+
+// RunDetector feeds all the data to the detector and collects the events
+func RunDetector(d Detector, data []float64) []Event {
+	events := make([]Event, 0)
+	for _, x := range data {
+		events = append(events, d.Update(x)...)
+	}
+	return events
+}
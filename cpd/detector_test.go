@@ -0,0 +1,62 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+// test OCPD and OnlineChangepointDetection give the same Result
+func TestResultFromR(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")[:300]
+
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	R, _ := OnlineChangepointDetection(data, 250, ConstantHazard, st)
+	offline := ResultFromR(&R, 0, 0.5)
+
+	st = NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	var d Detector = NewOCPD(250, ConstantHazardSlice, st).SetConfirmation(0, 0.5)
+	events := RunDetector(d, data)
+	online := d.(*OCPD).Result()
+
+	assert.Equal(t, events, online.Events)
+	assert.Equal(t, online.N, offline.N)
+	assert.Equal(t, online.Changepoints(), offline.Changepoints())
+	assert.Equal(t, []int{58, 249}, online.Changepoints())
+	assert.Equal(t, len(data), d.State().Step)
+
+	// without an event layer the events do not pile up
+	st = NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	plain := NewOCPD(250, ConstantHazardSlice, st)
+	assert.Empty(t, RunDetector(plain, data))
+	assert.Empty(t, plain.Events)
+}
+
+// test the detectors are scored by the evaluation package
+func TestEvaluateDetectors(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	R, _ := OnlineChangepointDetection(data, 250, ConstantHazard, st)
+	offline := ResultFromR(&R, 25, 0.5)
+	st = NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	online := NewOCPD(250, ConstantHazardSlice, st).SetConfirmation(25, 0.5)
+	RunDetector(online, data)
+
+	for _, res := range []Result{offline, online.Result()} {
+		r := evaluation.Evaluate(res.Changepoints(), truth, len(data), 5)
+		assert.Equal(t, 1.0, r.Precision)
+		assert.Equal(t, 1.0, r.Recall)
+		assert.Greater(t, r.Covering, 0.99)
+		assert.Greater(t, r.RandIndex, 0.99)
+		assert.LessOrEqual(t, r.Hausdorff, 5.0)
+		// the confirmation window delays the alarms
+		confirmed := make([]int, 0, len(res.Events))
+		for _, e := range res.Events {
+			confirmed = append(confirmed, e.Confirmed)
+		}
+		assert.InDelta(t, 25, evaluation.MeanDelay(confirmed, truth, 50), 3)
+	}
+}
@@ -0,0 +1,135 @@
+package cpd
+
+import "time"
+
+// * EventKind tells what kind of event the detector has emitted.
+type EventKind int
+
+const (
+	// Changepoint is a persistent shift: the posterior stayed on short run lengths.
+	Changepoint EventKind = iota
+	// Outlier is a transient spike: the posterior went back to the old segment.
+	Outlier
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Changepoint:
+		return "Changepoint"
+	case Outlier:
+		return "Outlier"
+	default:
+		return "Unknown"
+	}
+}
+
+// * Event is the output of the event layer on top of the run length posterior.
+// * All the indices are positions in the data stream, starting from 0.
+type Event struct {
+	Kind EventKind
+	// Index is the first observation of the new segment (or the spike itself)
+	Index int
+	// Detected is the step where the candidate was raised
+	Detected int
+	// Confirmed is the step where the decision was made
+	Confirmed int
+	// Latency is the number of steps between Index and Confirmed
+	Latency int
+	// Probability is the posterior mass on run lengths starting at or after Index
+	Probability float64
+	// Score is the detector specific statistic, e.g. the cost reduction of PELT
+	Score float64
+	// PValue is the significance of the detectors with a statistical test
+	PValue float64
+	// Time, DetectedTime and ConfirmedTime are the wall clock times of Index,
+	// Detected and Confirmed for timestamped data, see StampEvents
+	Time, DetectedTime, ConfirmedTime time.Time
+}
+
+// * confirmation keeps the candidate changepoints waiting for a decision.
+// * A candidate is raised when the argmax run length stops growing,
+// * then after window steps the mass on short run lengths decides its kind.
+type confirmation struct {
+	window    int
+	threshold float64
+	pending   []Event
+}
+
+// observe feeds the run length posterior of step t and returns the decided events.
+// prevMax is the argmax run length of the previous step, -1 for the first step.
+func (c *confirmation) observe(t int, res []float64, prevMax int) []Event {
+	return c.observeMass(t, ArgmaxSlice(res), prevMax, func(r int) float64 {
+		return SumSlice(res[:min(r+1, len(res))])
+	})
+}
+
+// observeMass is observe for any representation of the posterior: curMax is
+// its argmax run length and mass(r) is P(run length <= r).
+func (c *confirmation) observeMass(t, curMax, prevMax int, mass func(r int) float64) []Event {
+	// @ 1. raise a candidate if the run length does not grow
+	if prevMax >= 0 && curMax <= prevMax {
+		idx := t - curMax + 1
+		overlap := false
+		for _, e := range c.pending {
+			if idx <= e.Index+c.window {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			c.pending = append(c.pending, Event{Index: idx, Detected: t})
+		}
+	}
+	// @ 2. decide the candidates whose window is over
+	var out []Event
+	rest := c.pending[:0]
+	for _, e := range c.pending {
+		if t-e.Detected < c.window {
+			rest = append(rest, e)
+			continue
+		}
+		// @ run length r at step t means the segment starts at t-r+1
+		e.Probability = mass(t - e.Index + 1)
+		e.Confirmed = t
+		e.Latency = t - e.Index
+		if e.Probability >= c.threshold {
+			e.Kind = Changepoint
+		} else {
+			e.Kind = Outlier
+		}
+		out = append(out, e)
+	}
+	c.pending = rest
+	return out
+}
+
+// * fixedLag decides the changepoints with a known delay: at step t the mass
+// * of run lengths lag+1..lag+width+1 is the probability that the current
+// * segment started lag to lag+width steps ago, given the data up to t.
+// * A run length r counts x_t, so its segment started at t - r + 1.
+type fixedLag struct {
+	lag, width int
+	threshold  float64
+	last       int
+}
+
+// observe feeds the run length posterior of step t and returns the lagged
+// probability and the changepoint, if any
+func (f *fixedLag) observe(t int, res []float64) (float64, []Event) {
+	if len(res) <= f.lag+1 {
+		return 0, nil
+	}
+	window := res[f.lag+1 : min(f.lag+f.width+2, len(res))]
+	p := SumSlice(window)
+	if p < f.threshold {
+		return p, nil
+	}
+	// @ the most probable start inside the window, once per changepoint,
+	// @ the start of the series is not a changepoint
+	idx := t - f.lag - ArgmaxSlice(window)
+	if idx <= 0 || (f.last >= 0 && idx-f.last <= f.width) {
+		return p, nil
+	}
+	f.last = idx
+	return p, []Event{{Kind: Changepoint, Index: idx, Detected: t, Confirmed: t, Latency: t - idx, Probability: p}}
+}
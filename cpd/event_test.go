@@ -0,0 +1,52 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the confirmation window tells a spike apart from a shift
+func TestOCPDConfirmation(t *testing.T) {
+	// the first change point of ../data/data_output.csv is at index 58
+	data := MustReadData("../data/data_output.csv")
+	// inject a transient spike inside the first segment
+	data[30] += 10
+
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	cpd := NewOCPD(250, ConstantHazardSlice, st).SetConfirmation(25, 0.5)
+	for _, x := range data[:100] {
+		cpd.OCPD_Update(x)
+	}
+
+	assert.Equal(t, 2, len(cpd.Events))
+	assert.Equal(t, Outlier, cpd.Events[0].Kind)
+	assert.Equal(t, 30, cpd.Events[0].Index)
+	assert.Equal(t, 55, cpd.Events[0].Confirmed)
+	assert.Equal(t, Changepoint, cpd.Events[1].Kind)
+	assert.Equal(t, 58, cpd.Events[1].Index)
+	assert.Equal(t, 25, cpd.Events[1].Latency)
+	assert.Greater(t, cpd.Events[1].Probability, 0.99)
+}
+
+// test the fixed lag mode ignores a spike and reports the shift lag steps later
+func TestOCPDFixedLag(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	data[30] += 10
+
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	cpd := NewOCPD(250, ConstantHazardSlice, st).SetFixedLag(10, 2, 0.5)
+	for _, x := range data[:100] {
+		cpd.OCPD_Update(x)
+	}
+
+	assert.Equal(t, 100, len(cpd.Lagged))
+	// the spike has no mass left lag steps later
+	assert.Less(t, cpd.Lagged[40], 0.01)
+	assert.Equal(t, 1, len(cpd.Events))
+	assert.Equal(t, Changepoint, cpd.Events[0].Kind)
+	assert.Equal(t, 58, cpd.Events[0].Index)
+	assert.Equal(t, 68, cpd.Events[0].Confirmed)
+	assert.Equal(t, 10, cpd.Events[0].Latency)
+	assert.Greater(t, cpd.Events[0].Probability, 0.99)
+}
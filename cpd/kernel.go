@@ -0,0 +1,201 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+)
+
+// * KernelCost is the kernel segmentation cost of Arlot, Celisse and Harchaoui
+// * with the Gaussian RBF kernel k(x, y) = exp(-(x-y)^2 / (2 h^2)):
+// * the scatter of the segment in the feature space, sum k(x_i, x_i) - 1/n sum k(x_i, x_j).
+// * It sees the changes of the whole distribution, not only the mean and the variance.
+// * The Fit keeps the cumulative Gram matrix, so the memory is O(n^2).
+type KernelCost struct {
+	bandwidth float64
+	h         float64
+	// gram[i][j] is the sum of k over data[:i] x data[:j]
+	gram [][]float64
+}
+
+// NewKernelCost returns the RBF kernel cost, bandwidth <= 0 means the
+// median heuristic: the median distance between the data
+func NewKernelCost(bandwidth float64) *KernelCost { return &KernelCost{bandwidth: bandwidth} }
+
+func (c *KernelCost) Fit(data []float64) {
+	n := len(data)
+	c.h = c.bandwidth
+	if c.h <= 0 {
+		c.h = MedianHeuristic(data)
+	}
+	c.gram = make([][]float64, n+1)
+	c.gram[0] = make([]float64, n+1)
+	for i := 0; i < n; i++ {
+		c.gram[i+1] = make([]float64, n+1)
+		row := 0.0
+		for j := 0; j < n; j++ {
+			d := (data[i] - data[j]) / c.h
+			row += math.Exp(-d * d / 2)
+			c.gram[i+1][j+1] = c.gram[i][j+1] + row
+		}
+	}
+}
+
+func (c *KernelCost) Cost(start, end int) float64 {
+	n := float64(end - start)
+	inner := c.gram[end][end] - c.gram[start][end] - c.gram[end][start] + c.gram[start][start]
+	return n - inner/n
+}
+
+func (c *KernelCost) Params() int { return 1 }
+
+// Bandwidth returns the bandwidth used by the last Fit
+func (c *KernelCost) Bandwidth() float64 { return c.h }
+
+// MedianHeuristic returns the median of the pairwise distances, computed
+// on at most 1000 evenly spaced data for the long series
+func MedianHeuristic(data []float64) float64 {
+	sample := data
+	if len(data) > 1000 {
+		sample = make([]float64, 1000)
+		for i := range sample {
+			sample[i] = data[i*len(data)/1000]
+		}
+	}
+	dists := make([]float64, 0, len(sample)*(len(sample)-1)/2)
+	for i := range sample {
+		for j := i + 1; j < len(sample); j++ {
+			dists = append(dists, math.Abs(sample[i]-sample[j]))
+		}
+	}
+	if len(dists) == 0 {
+		return 1
+	}
+	if h := median(dists); h > 0 {
+		return h
+	}
+	return 1
+}
+
+// * KCP is the kernel changepoint detection: the exact PELT search with
+// * the RBF kernel cost and the median heuristic bandwidth.
+func KCP(data []float64, penalty Penalty, minSize int) Result {
+	res := PELT(data, NewKernelCost(0), penalty, minSize)
+	res.Method = "KCP"
+	return res
+}
+
+// * EDivisive is the energy statistic divisive detector of Matteson and James (2014).
+// * Every round splits the segment with the largest statistic
+// * Q = mn/(m+n) * (2/mn sum|x-y|^a - sum|x-x'|^a / C(m,2) - sum|y-y'|^a / C(n,2)),
+// * with the split searched over the whole segment, and keeps the split if
+// * the permutation test within the segments gives a p-value below sigLevel.
+// * alpha is in (0, 2), maxCps <= 0 means no limit.
+func EDivisive(data []float64, alpha float64, maxCps, minSize, permutations int, sigLevel float64, seed int64) Result {
+	if alpha <= 0 || alpha >= 2 {
+		panic("EDivisive needs alpha in (0, 2)")
+	}
+	n := len(data)
+	if minSize < 2 {
+		minSize = 2
+	}
+	rng := rand.New(rand.NewSource(seed))
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+
+	bounds := []int{0, n}
+	var cps []int
+	var scores, pvalues []float64
+	for maxCps <= 0 || len(cps) < maxCps {
+		// @ 1. the best split over all the segments
+		cp, stat := energyBestSplit(data, idx, bounds, alpha, minSize)
+		if cp < 0 {
+			break
+		}
+		// @ 2. the permutation test, shuffling within the segments
+		exceed := 0
+		perm := make([]int, n)
+		for r := 0; r < permutations; r++ {
+			copy(perm, idx)
+			for i := 0; i+1 < len(bounds); i++ {
+				seg := perm[bounds[i]:bounds[i+1]]
+				rng.Shuffle(len(seg), func(a, b int) { seg[a], seg[b] = seg[b], seg[a] })
+			}
+			if _, s := energyBestSplit(data, perm, bounds, alpha, minSize); s >= stat {
+				exceed++
+			}
+		}
+		pvalue := float64(exceed+1) / float64(permutations+1)
+		if pvalue > sigLevel {
+			break
+		}
+		cps = append(cps, cp)
+		scores = append(scores, stat)
+		pvalues = append(pvalues, pvalue)
+		bounds = insertSorted(bounds, cp)
+	}
+	res := NewOfflineResult("EDivisive", n, cps, scores)
+	for i := range res.Events {
+		for j, cp := range cps {
+			if res.Events[i].Index == cp {
+				res.Events[i].PValue = pvalues[j]
+			}
+		}
+	}
+	return res
+}
+
+// energyBestSplit returns the split with the largest energy statistic over
+// the segments between the bounds, data are read through idx
+func energyBestSplit(data []float64, idx []int, bounds []int, alpha float64, minSize int) (int, float64) {
+	dist := func(i, j int) float64 { return math.Pow(math.Abs(data[idx[i]]-data[idx[j]]), alpha) }
+	best, bestStat := -1, 0.0
+	for b := 0; b+1 < len(bounds); b++ {
+		s, e := bounds[b], bounds[b+1]
+		if e-s < 2*minSize {
+			continue
+		}
+		// @ start with everything on the right, then move the points to the left one by one
+		within, between, right := 0.0, 0.0, 0.0
+		for i := s; i < e; i++ {
+			for j := i + 1; j < e; j++ {
+				right += dist(i, j)
+			}
+		}
+		for tau := s + 1; tau <= e-minSize; tau++ {
+			p := tau - 1
+			toLeft, toRight := 0.0, 0.0
+			for j := s; j < p; j++ {
+				toLeft += dist(p, j)
+			}
+			for j := tau; j < e; j++ {
+				toRight += dist(p, j)
+			}
+			within += toLeft
+			right -= toRight
+			between += toRight - toLeft
+			m, k := float64(tau-s), float64(e-tau)
+			if tau-s < minSize {
+				continue
+			}
+			stat := m * k / (m + k) * (2*between/(m*k) - within/(m*(m-1)/2) - right/(k*(k-1)/2))
+			if best < 0 || stat > bestStat {
+				best, bestStat = tau, stat
+			}
+		}
+	}
+	return best, bestStat
+}
+
+// insertSorted inserts v in the sorted slice
+func insertSorted(s []int, v int) []int {
+	i := 0
+	for i < len(s) && s[i] < v {
+		i++
+	}
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
@@ -0,0 +1,53 @@
+package cpd
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// shapeChange returns a unimodal segment, then a bimodal one with the same mean and variance
+func shapeChange(seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	data := make([]float64, 0, 400)
+	for i := 0; i < 200; i++ {
+		data = append(data, rng.NormFloat64())
+	}
+	for i := 0; i < 200; i++ {
+		mode := 0.95
+		if rng.Intn(2) == 0 {
+			mode = -0.95
+		}
+		data = append(data, mode+0.3*rng.NormFloat64())
+	}
+	return data
+}
+
+// test the kernel and energy detectors see the change of shape
+func TestKernelDetectors(t *testing.T) {
+	data := shapeChange(5)
+
+	// the Gaussian likelihood does not see it
+	assert.Empty(t, PELT(data, NewMeanVarCost(), Penalty{Kind: MBIC}, 5).Changepoints())
+
+	// the kernel cost is at most 1 per datum, so the penalty is small
+	res := KCP(data, Penalty{Kind: ManualPenalty, Value: 2}, 10)
+	assert.Equal(t, "KCP", res.Method)
+	assert.Equal(t, []int{200}, res.Changepoints())
+
+	res = EDivisive(data, 1, 0, 30, 99, 0.05, 1)
+	assert.Equal(t, "EDivisive", res.Method)
+	assert.Equal(t, []int{200}, res.Changepoints())
+	assert.LessOrEqual(t, res.Events[0].PValue, 0.05)
+	assert.Greater(t, res.Events[0].Score, 0.0)
+}
+
+// test the median heuristic and the kernel cost of a constant segment
+func TestKernelCost(t *testing.T) {
+	assert.InDelta(t, 1.0, MedianHeuristic([]float64{0, 1, 2}), 1e-12)
+	c := NewKernelCost(0)
+	c.Fit([]float64{3, 3, 3, 3, 0, 1, 2})
+	assert.InDelta(t, 0.0, c.Cost(0, 4), 1e-12)
+	assert.Greater(t, c.Cost(2, 7), 0.0)
+}
@@ -0,0 +1,90 @@
+package cpd
+
+import "math"
+
+// * MAP is the single most probable segmentation of OfflineBOCPD, by the
+// * max-product version of the forward recursion with backtracking:
+// * M(t) = max over s < t of M(s) + log P(x_s..x_t-1) + log g(t-s).
+// * The data and a fresh observation model with the same prior give the
+// * posterior parameters of every segment, if the model is a ParameterModel.
+// * The Probability of an event is the smoothed changepoint probability.
+func (ob *OfflineBOCPD) MAP(data []float64, model ObservationModel) Result {
+	n := ob.n
+	if len(data) != n {
+		panic("The data must be the same as the data of OfflineBOCPD")
+	}
+	if n == 0 {
+		return NewOfflineResult("MAP", 0, nil, nil)
+	}
+	// @ 1. the max-product recursion, M(0) = 0
+	M := make([]float64, n+1)
+	last := make([]int, n+1)
+	for t := 1; t <= n; t++ {
+		M[t] = math.Inf(-1)
+		for s := 0; s < t; s++ {
+			v := M[s] + ob.segLL[s][t-1-s]
+			// @ the last segment only needs to survive
+			if t == n {
+				v += ob.logG[t-s]
+			} else {
+				v += ob.logg(t - s)
+			}
+			if v > M[t] {
+				M[t], last[t] = v, s
+			}
+		}
+	}
+	// @ 2. backtrack the changepoints
+	var cps []int
+	for s := last[n]; s > 0; s = last[s] {
+		cps = append([]int{s}, cps...)
+	}
+	res := NewOfflineResult("MAP", n, cps, nil)
+	probs := ob.ChangepointProbabilities()
+	for i := range res.Events {
+		res.Events[i].Probability = probs[res.Events[i].Index]
+	}
+	res.Segments = segmentsOf(data, cps, model)
+	return res
+}
+
+// LogJoint returns log P(segmentation, data) of the changepoints
+func (ob *OfflineBOCPD) LogJoint(cps []int) float64 {
+	bounds := append(append([]int{0}, cps...), ob.n)
+	res := 0.0
+	for i := 0; i+1 < len(bounds); i++ {
+		s, e := bounds[i], bounds[i+1]
+		res += ob.segLL[s][e-1-s]
+		if e == ob.n {
+			res += ob.logG[e-s]
+		} else {
+			res += ob.logg(e - s)
+		}
+	}
+	return res
+}
+
+// segmentsOf runs the model over the data once: at the end of every segment
+// the parameter set of its length is the posterior of the segment alone
+func segmentsOf(data []float64, cps []int, model ObservationModel) []Segment {
+	bounds := append(append([]int{0}, cps...), len(data))
+	segments := make([]Segment, 0, len(bounds)-1)
+	pm, ok := model.(ParameterModel)
+	for t, x := range data {
+		model.UpdateTheta([]float64{x})
+		start := bounds[len(segments)]
+		end := bounds[len(segments)+1]
+		if t+1 != end {
+			continue
+		}
+		seg := Segment{Start: start, End: end, From: float64(start), To: float64(end)}
+		if ok {
+			seg.Value = pm.Mean(end - start)
+			seg.Params = pm.Params(end - start)
+		} else {
+			seg.Value = SumSlice(data[start:end]) / float64(end-start)
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
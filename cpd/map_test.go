@@ -0,0 +1,48 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the MAP segmentation of ../data/data_output.csv
+func TestOfflineBOCPDMAP(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+	newModel := func() *StudentT_Bayesian_Update {
+		return NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	}
+	ob := NewOfflineBOCPD(data, 250, ConstantHazardSlice, newModel())
+
+	res := ob.MAP(data, newModel())
+	assert.Equal(t, "MAP", res.Method)
+	cps := res.Changepoints()
+	assert.Equal(t, len(truth), len(cps))
+	for i, cp := range cps {
+		assert.InDelta(t, truth[i], cp, 3)
+	}
+	// the MAP beats the truth and the sampled segmentations
+	best := ob.LogJoint(cps)
+	assert.GreaterOrEqual(t, best, ob.LogJoint(truth))
+	for _, s := range ob.Sample(50, 1) {
+		assert.GreaterOrEqual(t, best+1e-9, ob.LogJoint(s))
+	}
+	assert.Less(t, best, ob.LogEvidence())
+
+	// the segments cover the series with the posterior of each segment
+	assert.Equal(t, len(cps)+1, len(res.Segments))
+	assert.Equal(t, 0, res.Segments[0].Start)
+	assert.Equal(t, len(data), res.Segments[len(cps)].End)
+	for i, seg := range res.Segments {
+		// mu shrinks to the prior mean 0 with kappa 1
+		n := float64(seg.End - seg.Start)
+		assert.InDelta(t, SumSlice(data[seg.Start:seg.End])/(n+1), seg.Value, 1e-9)
+		// mu, kappa, alpha, beta and the weight of the single prior
+		assert.Equal(t, 5, len(seg.Params))
+		assert.InDelta(t, 1+n, seg.Params[1], 1e-9)
+		if i > 0 {
+			assert.Equal(t, res.Segments[i-1].End, seg.Start)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package cpd
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// * ObservationModel is the part of BOCPD that knows the data distribution.
+// * The parameter sets are indexed by run length: the first one is the prior,
+// * and every UpdateTheta pushes the prior to the head and updates the others.
+// * StudentT_Bayesian_Update is the original implementation.
+type ObservationModel interface {
+	// PDF returns the predictive density of each datum under each parameter set
+	PDF(data []float64) [][]float64
+	// UpdateTheta updates the parameter sets with the new data
+	UpdateTheta(data []float64)
+}
+
+// * ParameterModel is an ObservationModel which reports its posterior
+// * parameters, the index r is the same as the parameter sets of PDF.
+type ParameterModel interface {
+	ObservationModel
+	// Mean returns the location of the predictive distribution of the next datum
+	Mean(r int) float64
+	// Params returns the posterior parameters of the parameter set r
+	Params(r int) []float64
+}
+
+// * PrunableModel is an ObservationModel which can drop parameter sets,
+// * so an approximate run length posterior only pays for its support.
+type PrunableModel interface {
+	ObservationModel
+	// Keep keeps the parameter sets of the indices, in that order
+	Keep(indices []int)
+}
+
+// * SkippableModel is an ObservationModel whose parameter sets also depend on
+// * the position in the segment, which a missing value has to advance.
+type SkippableModel interface {
+	ObservationModel
+	// Skip moves every parameter set one step on without data
+	Skip()
+}
+
+// * LinearRegression_Bayesian_Update is the Bayesian linear regression with
+// * a Normal-Inverse-Gamma prior: x = phi * w + e, e ~ N(0, s2),
+// * w | s2 ~ N(m, s2 * sigma), s2 ~ InvGamma(a, b).
+// * The features phi of the next datum come from its position in the segment
+// * and the last observations, so the same struct gives trend and AR models.
+type LinearRegression_Bayesian_Update struct {
+	features func(r int, hist []float64) []float64
+	lags     int
+	hist     []float64
+	// one entry per parameter set, the head is the prior
+	runs  []int
+	m     [][]float64
+	sigma []*mat.SymDense
+	a, b  []float64
+}
+
+// NewLinearRegression_BU creates a regression model with prior mean m0 and
+// prior variances v0 of the weights (in units of the noise variance).
+// features gets the run length r, which is the position of the next datum
+// in its segment, and the last lags observations with the latest at the end.
+func NewLinearRegression_BU(alpha, beta float64, m0, v0 []float64, lags int, features func(r int, hist []float64) []float64) *LinearRegression_Bayesian_Update {
+	if len(m0) != len(v0) {
+		panic("Parameters m0 and v0 must have the same length")
+	}
+	sigma0 := mat.NewSymDense(len(v0), nil)
+	for i, v := range v0 {
+		sigma0.SetSym(i, i, v)
+	}
+	m := make([]float64, len(m0))
+	copy(m, m0)
+	return &LinearRegression_Bayesian_Update{
+		features: features,
+		lags:     lags,
+		hist:     make([]float64, 0, lags),
+		runs:     []int{0},
+		m:        [][]float64{m},
+		sigma:    []*mat.SymDense{sigma0},
+		a:        []float64{alpha},
+		b:        []float64{beta},
+	}
+}
+
+// NewTrend_BU creates a local linear trend model: x = w0 + w1 * position in segment
+func NewTrend_BU(alpha, beta float64, m0, v0 []float64) *LinearRegression_Bayesian_Update {
+	if len(m0) != 2 {
+		panic("Trend model needs 2 weights: intercept and slope")
+	}
+	return NewLinearRegression_BU(alpha, beta, m0, v0, 0, func(r int, hist []float64) []float64 {
+		return []float64{1, float64(r)}
+	})
+}
+
+// NewAR_BU creates an AR(p) model: x = w0 + w1 * x[t-1] + ... + wp * x[t-p].
+// The missing lags at the head of the stream are treated as 0.
+func NewAR_BU(p int, alpha, beta float64, m0, v0 []float64) *LinearRegression_Bayesian_Update {
+	if len(m0) != p+1 {
+		panic("AR(p) model needs p+1 weights: intercept and the lags")
+	}
+	return NewLinearRegression_BU(alpha, beta, m0, v0, p, func(r int, hist []float64) []float64 {
+		phi := make([]float64, p+1)
+		phi[0] = 1
+		for i := 1; i <= p && i <= len(hist); i++ {
+			phi[i] = hist[len(hist)-i]
+		}
+		return phi
+	})
+}
+
+// * Method: PDF computes the Student's t predictive density of the data,
+// * the output has the same layout as StudentT_Bayesian_Update.PDF
+func (lr *LinearRegression_Bayesian_Update) PDF(data []float64) [][]float64 {
+	res := make([][]float64, len(lr.a))
+	for i := range lr.a {
+		phi := mat.NewVecDense(len(lr.m[i]), lr.features(lr.runs[i], lr.hist))
+		loc := mat.Dot(phi, mat.NewVecDense(len(lr.m[i]), lr.m[i]))
+		scale := math.Sqrt(lr.b[i] / lr.a[i] * (1 + mat.Inner(phi, lr.sigma[i], phi)))
+		tDist := distuv.StudentsT{Mu: loc, Sigma: scale, Nu: 2 * lr.a[i]}
+		pdfs := make([]float64, len(data))
+		for j, x := range data {
+			pdfs[j] = tDist.Prob(x)
+		}
+		res[i] = pdfs
+	}
+	return res
+}
+
+// * Method: UpdateTheta does the rank one update for each run length
+// * and pushes the prior to the head, one datum after the other.
+func (lr *LinearRegression_Bayesian_Update) UpdateTheta(data []float64) {
+	for _, x := range data {
+		n := len(lr.a)
+		d := len(lr.m[0])
+		runs := make([]int, n+1)
+		m := make([][]float64, n+1)
+		sigma := make([]*mat.SymDense, n+1)
+		a := make([]float64, n+1)
+		b := make([]float64, n+1)
+		// @ the prior stays at the head
+		m[0], sigma[0], a[0], b[0] = lr.m[0], lr.sigma[0], lr.a[0], lr.b[0]
+		for i := 0; i < n; i++ {
+			phi := mat.NewVecDense(d, lr.features(lr.runs[i], lr.hist))
+			mu := mat.NewVecDense(d, lr.m[i])
+			// @ s = 1 + phi' sigma phi, k = sigma phi / s, e = x - phi' m
+			k := mat.NewVecDense(d, nil)
+			k.MulVec(lr.sigma[i], phi)
+			s := 1 + mat.Dot(phi, k)
+			k.ScaleVec(1/s, k)
+			e := x - mat.Dot(phi, mu)
+			// @ m' = m + k e, sigma' = sigma - s k k', a' = a + 1/2, b' = b + e^2 / 2s
+			mNew := mat.NewVecDense(d, nil)
+			mNew.AddScaledVec(mu, e, k)
+			sNew := mat.NewSymDense(d, nil)
+			sNew.SymRankOne(lr.sigma[i], -s, k)
+			runs[i+1] = lr.runs[i] + 1
+			m[i+1] = TransformVecDenseToSlice(mNew)
+			sigma[i+1] = sNew
+			a[i+1] = lr.a[i] + 0.5
+			b[i+1] = lr.b[i] + e*e/(2*s)
+		}
+		lr.runs, lr.m, lr.sigma, lr.a, lr.b = runs, m, sigma, a, b
+		// @ keep the last lags observations for the features
+		if lr.lags > 0 {
+			lr.hist = append(lr.hist, x)
+			if len(lr.hist) > lr.lags {
+				lr.hist = lr.hist[len(lr.hist)-lr.lags:]
+			}
+		}
+	}
+}
+
+// * Method: Skip moves the positions on for a missing value, the weights
+// * and the history of the lags are left as they are
+func (lr *LinearRegression_Bayesian_Update) Skip() {
+	for i := range lr.runs {
+		lr.runs[i]++
+	}
+}
+
+// * Method: Mean returns phi' m, the location of the predictive distribution
+func (lr *LinearRegression_Bayesian_Update) Mean(r int) float64 {
+	return mat.Dot(mat.NewVecDense(len(lr.m[r]), lr.features(lr.runs[r], lr.hist)), mat.NewVecDense(len(lr.m[r]), lr.m[r]))
+}
+
+// * Method: Params returns the weights m followed by a and b
+func (lr *LinearRegression_Bayesian_Update) Params(r int) []float64 {
+	return append(append([]float64{}, lr.m[r]...), lr.a[r], lr.b[r])
+}
+
+// * Method: Keep keeps the parameter sets of the indices, in that order
+func (lr *LinearRegression_Bayesian_Update) Keep(indices []int) {
+	runs := make([]int, len(indices))
+	m := make([][]float64, len(indices))
+	sigma := make([]*mat.SymDense, len(indices))
+	a := make([]float64, len(indices))
+	b := make([]float64, len(indices))
+	for i, r := range indices {
+		runs[i], m[i], sigma[i], a[i], b[i] = lr.runs[r], lr.m[r], lr.sigma[r], lr.a[r], lr.b[r]
+	}
+	lr.runs, lr.m, lr.sigma, lr.a, lr.b = runs, m, sigma, a, b
+}
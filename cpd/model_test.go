@@ -0,0 +1,47 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the regression model with only the intercept is the Student-t model
+func TestLinearRegressionMatchesStudentT(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")[:80]
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	lr := NewLinearRegression_BU(0.1, 0.01, []float64{0}, []float64{1}, 0, func(r int, hist []float64) []float64 {
+		return []float64{1}
+	})
+	var _ ObservationModel = lr
+	for _, x := range data {
+		p1 := st.PDF([]float64{x})
+		p2 := lr.PDF([]float64{x})
+		assert.Equal(t, len(p1), len(p2))
+		for i := range p1 {
+			assert.InDelta(t, p1[i][0], p2[i][0], 1e-9)
+		}
+		st.UpdateTheta([]float64{x})
+		lr.UpdateTheta([]float64{x})
+	}
+}
+
+// test Keep drops parameter sets but keeps the run length of the others
+func TestLinearRegressionKeep(t *testing.T) {
+	full := NewTrend_BU(1, 1, []float64{0, 0}, []float64{10, 1})
+	pruned := NewTrend_BU(1, 1, []float64{0, 0}, []float64{10, 1})
+	for i := 0; i < 5; i++ {
+		full.UpdateTheta([]float64{float64(i)})
+		pruned.UpdateTheta([]float64{float64(i)})
+	}
+	pruned.Keep([]int{0, 2, 5})
+	pruned.UpdateTheta([]float64{5})
+	full.UpdateTheta([]float64{5})
+	want := full.PDF([]float64{6})
+	got := pruned.PDF([]float64{6})
+	assert.Equal(t, 4, len(got))
+	for i, r := range []int{0, 1, 3, 6} {
+		assert.InDelta(t, want[r][0], got[i][0], 1e-12)
+		assert.Equal(t, full.Params(r), pruned.Params(i))
+	}
+}
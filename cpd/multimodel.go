@@ -0,0 +1,111 @@
+package cpd
+
+import "time"
+
+// * MultiModelOCPD is the online detector where every hypothesis is a
+// * (run length, model) pair. After a changepoint the new segment picks one
+// * of the observation models with the prior model weights, so the posterior
+// * also tells which kind of regime the current segment is.
+type MultiModelOCPD struct {
+	lam            float64
+	hazardFunction func(float64, []float64) []float64
+	models         []ObservationModel
+	weights        []float64
+	// joint[m][r] is the probability of run length r under model m
+	joint [][]float64
+	clock clock
+	// result part
+	Res        []float64
+	Maxes      []float64
+	ModelProbs [][]float64
+	// Times are the timestamps of the steps fed by UpdateAt, aligned with Maxes
+	Times []time.Time
+}
+
+// NewMultiModelOCPD returns a new MultiModelOCPD.
+// weights are the prior model weights, they will be normalized.
+func NewMultiModelOCPD(lam float64, hazardFunction func(float64, []float64) []float64, models []ObservationModel, weights []float64) *MultiModelOCPD {
+	if len(models) == 0 || len(models) != len(weights) {
+		panic("Models and weights must be non-empty and have the same length")
+	}
+	w := NormalizeSlice(append([]float64{}, weights...))
+	joint := make([][]float64, len(models))
+	for m := range models {
+		joint[m] = []float64{w[m]}
+	}
+	return &MultiModelOCPD{
+		lam:            lam,
+		hazardFunction: hazardFunction,
+		models:         models,
+		weights:        w,
+		joint:          joint,
+
+		Res:        []float64{1.0},
+		Maxes:      make([]float64, 0),
+		ModelProbs: make([][]float64, 0),
+	}
+}
+
+// SetTimeScale makes lam the expected segment length in units of time,
+// UpdateAt then scales the hazard by the elapsed time, as OCPD.SetTimeScale
+func (cpd *MultiModelOCPD) SetTimeScale(unit time.Duration) *MultiModelOCPD {
+	cpd.clock.setUnit(unit)
+	return cpd
+}
+
+// UpdateAt feeds a value with its timestamp, as OCPD.UpdateAt; the detector
+// has no events, Times holds the timestamps
+func (cpd *MultiModelOCPD) UpdateAt(t time.Time, x float64) {
+	cpd.Times = cpd.clock.tick(cpd.Times, t)
+	cpd.OCPD_Update(x)
+	cpd.clock.done()
+}
+
+// OCPD_Update runs one step of the run length recursion over all the models
+func (cpd *MultiModelOCPD) OCPD_Update(data float64) {
+	// @ 1. Evaluate the growth probabilities of each model and
+	// @    collect the changepoint mass from all of them
+	growth := make([][]float64, len(cpd.models))
+	cp := 0.0
+	for m, model := range cpd.models {
+		predprobs := GetSliceFrom2dInnerSlice(model.PDF([]float64{data}), 0)
+		H := cpd.clock.hazard(cpd.hazardFunction(cpd.lam, predprobs))
+		tmp := MulSlice(cpd.joint[m], predprobs)
+		growth[m] = MulSlice(tmp, AddConstantSlice(MulConstantSlice(H, -1), 1))
+		cp += SumSlice(MulSlice(tmp, H))
+	}
+	// @ 2. The new segment picks the model with the prior model weights
+	total := 0.0
+	for m := range cpd.models {
+		cpd.joint[m] = append([]float64{cp * cpd.weights[m]}, growth[m]...)
+		total += SumSlice(cpd.joint[m])
+	}
+	// @ 3. Normalize the joint probabilities and marginalize them
+	cpd.Res = make([]float64, len(cpd.joint[0]))
+	probs := make([]float64, len(cpd.models))
+	for m := range cpd.models {
+		cpd.joint[m] = MulConstantSlice(cpd.joint[m], 1/total)
+		cpd.Res = AddSlice(cpd.Res, cpd.joint[m])
+		probs[m] = SumSlice(cpd.joint[m])
+	}
+	// @ 4. Update the parameter set of each model
+	for _, model := range cpd.models {
+		model.UpdateTheta([]float64{data})
+	}
+	// @ 5. Store the argmax run length and the model probabilities
+	cpd.Maxes = append(cpd.Maxes, float64(ArgmaxSlice(cpd.Res)))
+	cpd.ModelProbs = append(cpd.ModelProbs, probs)
+}
+
+// Joint returns a copy of the run length probabilities of model m
+func (cpd *MultiModelOCPD) Joint(m int) []float64 {
+	return append([]float64{}, cpd.joint[m]...)
+}
+
+// CurrentModel returns the index of the most probable model for the current step
+func (cpd *MultiModelOCPD) CurrentModel() int {
+	if len(cpd.ModelProbs) == 0 {
+		return ArgmaxSlice(cpd.weights)
+	}
+	return ArgmaxSlice(cpd.ModelProbs[len(cpd.ModelProbs)-1])
+}
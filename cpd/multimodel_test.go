@@ -0,0 +1,42 @@
+package cpd
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the MultiModelOCPD tells the constant mean regime from the trend and AR ones
+func TestMultiModelOCPD(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	var data []float64
+	// constant mean, then a trend, then an AR(1) regime
+	for i := 0; i < 150; i++ {
+		data = append(data, 10+0.5*rng.NormFloat64())
+	}
+	for i := 0; i < 150; i++ {
+		data = append(data, 20+0.2*float64(i)+0.5*rng.NormFloat64())
+	}
+	x := 0.0
+	for i := 0; i < 150; i++ {
+		x = 0.9*x + 2*rng.NormFloat64()
+		data = append(data, x)
+	}
+
+	models := []ObservationModel{
+		NewStudentT_BU([]float64{1}, []float64{1}, []float64{0.01}, []float64{0}),
+		NewTrend_BU(1, 1, []float64{0, 0}, []float64{100, 1}),
+		NewAR_BU(1, 1, 1, []float64{0, 0}, []float64{100, 1}),
+	}
+	cpd := NewMultiModelOCPD(250, ConstantHazardSlice, models, []float64{1, 1, 1})
+	for i, x := range data {
+		cpd.OCPD_Update(x)
+		assert.InDelta(t, 1.0, SumSlice(cpd.Res), 1e-9)
+		assert.InDelta(t, 1.0, SumSlice(cpd.ModelProbs[i]), 1e-9)
+	}
+	assert.Equal(t, 0, ArgmaxSlice(cpd.ModelProbs[149]))
+	assert.Equal(t, 1, ArgmaxSlice(cpd.ModelProbs[299]))
+	assert.Equal(t, 2, cpd.CurrentModel())
+	assert.Equal(t, len(data)+1, len(cpd.Joint(0)))
+}
@@ -0,0 +1,133 @@
+package cpd
+
+import "math"
+
+// * OfflineBOCPD is the exact Bayesian changepoint model of Fearnhead (2006)
+// * over the whole series, with the same observation models and hazards as OCPD.
+// * The segment likelihoods come from one pass of the observation model:
+// * the predictive density of x_t with r observations before it in the segment.
+// * The memory is O(n^2), as the likelihood of every segment is kept.
+type OfflineBOCPD struct {
+	n int
+	// segLL[s][k] is log P(x_s..x_s+k), the segment starting at s
+	segLL [][]float64
+	// logH[r] and log1mH[r] are the log hazard of a segment with r+1 observations
+	logH, log1mH []float64
+	// logG[l] is log P(length >= l) of a segment
+	logG []float64
+	// logF[t] is log P(a segment starts at t, x_0..x_t-1), logQ[t] is log P(x_t..x_n-1 | a segment starts at t)
+	logF, logQ []float64
+}
+
+// NewOfflineBOCPD runs the observation model over the data and the
+// forward and backward recursions. The model is consumed, pass a fresh one.
+func NewOfflineBOCPD(data []float64, lam float64, hazardFunction func(float64, []float64) []float64, model ObservationModel) *OfflineBOCPD {
+	n := len(data)
+	ob := &OfflineBOCPD{n: n, segLL: make([][]float64, n)}
+	// @ 1. the segment likelihoods from the predictive densities
+	for t, x := range data {
+		pdfs := GetSliceFrom2dInnerSlice(model.PDF([]float64{x}), 0)
+		ob.segLL[t] = make([]float64, 0, n-t)
+		for r := 0; r <= t; r++ {
+			s := t - r
+			prev := 0.0
+			if r > 0 {
+				prev = ob.segLL[s][r-1]
+			}
+			ob.segLL[s] = append(ob.segLL[s], prev+math.Log(pdfs[r]))
+		}
+		model.UpdateTheta([]float64{x})
+	}
+	// @ 2. the hazard of every run length
+	runLengths := make([]float64, n)
+	for i := range runLengths {
+		runLengths[i] = float64(i)
+	}
+	H := hazardFunction(lam, runLengths)
+	ob.logH = make([]float64, n)
+	ob.log1mH = make([]float64, n)
+	for r, h := range H {
+		ob.logH[r] = math.Log(h)
+		ob.log1mH[r] = math.Log1p(-h)
+	}
+	// @ 3. log G(l) = log P(length >= l), log g(l) = log G(l) + log H(l-1)
+	ob.logG = make([]float64, n+1)
+	for l := 2; l <= n; l++ {
+		ob.logG[l] = ob.logG[l-1] + ob.log1mH[l-2]
+	}
+	// @ 4. forward: F(t) = sum over s < t of F(s) P(x_s..x_t-1) g(t-s), F(0) = 1
+	ob.logF = make([]float64, n)
+	for t := 1; t < n; t++ {
+		terms := make([]float64, t)
+		for s := 0; s < t; s++ {
+			terms[s] = ob.logF[s] + ob.segLL[s][t-1-s] + ob.logg(t-s)
+		}
+		ob.logF[t] = logSumExp(terms)
+	}
+	// @ 5. backward: Q(t) = sum over s of P(x_t..x_s) g(s-t+1) Q(s+1) + P(x_t..x_n-1) G(n-t)
+	ob.logQ = make([]float64, n+1)
+	for t := n - 1; t >= 0; t-- {
+		terms := make([]float64, 0, n-t)
+		for s := t; s < n-1; s++ {
+			terms = append(terms, ob.segLL[t][s-t]+ob.logg(s-t+1)+ob.logQ[s+1])
+		}
+		terms = append(terms, ob.segLL[t][n-1-t]+ob.logG[n-t])
+		ob.logQ[t] = logSumExp(terms)
+	}
+	return ob
+}
+
+// logg returns log P(length = l) of a segment
+func (ob *OfflineBOCPD) logg(l int) float64 {
+	return ob.logG[l] + ob.logH[l-1]
+}
+
+// LogEvidence returns log P(x_0..x_n-1)
+func (ob *OfflineBOCPD) LogEvidence() float64 {
+	if ob.n == 0 {
+		return 0
+	}
+	return ob.logQ[0]
+}
+
+// ChangepointProbabilities returns the smoothed P(a new segment starts at t | all data)
+// for every t, the first one is 0 as the first segment always starts there.
+func (ob *OfflineBOCPD) ChangepointProbabilities() []float64 {
+	probs := make([]float64, ob.n)
+	for t := 1; t < ob.n; t++ {
+		probs[t] = math.Exp(ob.logF[t] + ob.logQ[t] - ob.logQ[0])
+	}
+	return probs
+}
+
+// Result returns the indices with the changepoint probability at least threshold
+func (ob *OfflineBOCPD) Result(threshold float64) Result {
+	probs := ob.ChangepointProbabilities()
+	var cps []int
+	for t, p := range probs {
+		if t > 0 && p >= threshold {
+			cps = append(cps, t)
+		}
+	}
+	res := NewOfflineResult("OfflineBOCPD", ob.n, cps, nil)
+	for i := range res.Events {
+		res.Events[i].Probability = probs[res.Events[i].Index]
+	}
+	return res
+}
+
+// logSumExp returns log(sum(exp(x))) without overflow
+func logSumExp(x []float64) float64 {
+	m := math.Inf(-1)
+	for _, v := range x {
+		m = math.Max(m, v)
+	}
+	if math.IsInf(m, -1) {
+		return m
+	}
+	sum := 0.0
+	for _, v := range x {
+		sum += math.Exp(v - m)
+	}
+	return m + math.Log(sum)
+}
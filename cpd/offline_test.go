@@ -0,0 +1,38 @@
+package cpd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the smoothed changepoint probabilities of ../data/data_output.csv
+func TestOfflineBOCPD(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	ob := NewOfflineBOCPD(data, 250, ConstantHazardSlice, st)
+	probs := ob.ChangepointProbabilities()
+	assert.Equal(t, len(data), len(probs))
+	assert.Equal(t, 0.0, probs[0])
+	for _, cp := range truth {
+		// the location of the small shift at 132 is less sure, the mass is around it
+		assert.Greater(t, SumSlice(probs[cp-3:cp+4]), 0.9)
+	}
+	res := ob.Result(0.5)
+	assert.Equal(t, []int{58, 249, 402, 539, 668}, res.Changepoints())
+	assert.Greater(t, res.Events[0].Probability, 0.99)
+
+	// the evidence is the product of the one step predictive densities of OCPD
+	st = NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	cpd := NewOCPD(250, ConstantHazardSlice, st)
+	logEvidence := 0.0
+	for _, x := range data {
+		pred := GetSliceFrom2dInnerSlice(st.PDF([]float64{x}), 0)
+		logEvidence += math.Log(SumSlice(MulSlice(cpd.Res, pred)))
+		cpd.OCPD_Update(x)
+	}
+	assert.InDelta(t, logEvidence, ob.LogEvidence(), 1e-6)
+}
@@ -0,0 +1,233 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// * ParticleOCPD approximates the run length posterior of OCPD with at most
+// * N particles, by the optimal resampling of Fearnhead & Liu (2007).
+// * Each step costs O(N) whatever the length of the stream: the particles
+// * which are not kept are also dropped from the observation model.
+type ParticleOCPD struct {
+	lam            float64
+	hazardFunction func(float64, []float64) []float64
+	model          PrunableModel
+	particles      int
+	rng            *rand.Rand
+	conf           *confirmation
+	clock          clock
+	// result part
+	// RunLengths is the support of the posterior, ascending, and Res its weights
+	RunLengths []int
+	Res        []float64
+	Maxes      []float64
+	Events     []Event
+	// Times are the timestamps of the steps fed by UpdateAt, aligned with Maxes
+	Times []time.Time
+}
+
+// NewParticleOCPD returns a particle filter with at most particles particles
+func NewParticleOCPD(lam float64, hazardFunction func(float64, []float64) []float64, model PrunableModel, particles int, seed int64) *ParticleOCPD {
+	if particles < 1 {
+		panic("The number of particles must be positive")
+	}
+	return &ParticleOCPD{
+		lam:            lam,
+		hazardFunction: hazardFunction,
+		model:          model,
+		particles:      particles,
+		rng:            rand.New(rand.NewSource(seed)),
+
+		RunLengths: []int{0},
+		Res:        []float64{1.0},
+		Maxes:      make([]float64, 0),
+		Events:     make([]Event, 0),
+	}
+}
+
+// SetConfirmation sets the confirmation window for the event layer, as
+// OCPD.SetConfirmation, without it the filter emits no events
+func (pf *ParticleOCPD) SetConfirmation(window int, threshold float64) *ParticleOCPD {
+	if window < 0 || threshold < 0 || threshold > 1 {
+		panic("window must be non-negative and threshold must be in [0, 1]")
+	}
+	pf.conf = &confirmation{window: window, threshold: threshold}
+	return pf
+}
+
+// SetTimeScale makes lam the expected segment length in units of time,
+// UpdateAt then scales the hazard by the elapsed time, as OCPD.SetTimeScale
+func (pf *ParticleOCPD) SetTimeScale(unit time.Duration) *ParticleOCPD {
+	pf.clock.setUnit(unit)
+	return pf
+}
+
+// UpdateAt feeds a value with its timestamp, it makes ParticleOCPD a
+// TimedDetector, as OCPD.UpdateAt
+func (pf *ParticleOCPD) UpdateAt(t time.Time, x float64) []Event {
+	pf.Times = pf.clock.tick(pf.Times, t)
+	n := len(pf.Events)
+	pf.OCPD_Update(x)
+	pf.clock.done()
+	StampEvents(pf.Events[n:], pf.Times)
+	return append([]Event{}, pf.Events[n:]...)
+}
+
+// OCPD_Update does the OCPD step over the particles, then resamples.
+// A NaN or infinite value is a missing one: the segments go on through it,
+// the run lengths grow by one, the weights are left as they are and so is
+// the model, but for the positions of a SkippableModel.
+func (pf *ParticleOCPD) OCPD_Update(data float64) {
+	if math.IsNaN(data) || math.IsInf(data, 0) {
+		for i := range pf.RunLengths {
+			pf.RunLengths[i]++
+		}
+		if m, ok := pf.model.(SkippableModel); ok {
+			m.Skip()
+		}
+	} else {
+		pf.step(data)
+	}
+	// @ the argmax run length and the events
+	prevMax := -1
+	if len(pf.Maxes) > 0 {
+		prevMax = int(pf.Maxes[len(pf.Maxes)-1])
+	}
+	curMax := pf.RunLengths[ArgmaxSlice(pf.Res)]
+	pf.Maxes = append(pf.Maxes, float64(curMax))
+	if pf.conf == nil {
+		return
+	}
+	pf.Events = append(pf.Events, pf.conf.observeMass(len(pf.Maxes)-1, curMax, prevMax, func(r int) float64 {
+		mass := 0.0
+		for i, rl := range pf.RunLengths {
+			if rl <= r {
+				mass += pf.Res[i]
+			}
+		}
+		return mass
+	})...)
+}
+
+// step updates the particles and the model with the observation
+func (pf *ParticleOCPD) step(data float64) {
+	// @ 1. the predictive density and the hazard of each particle
+	predprobs := GetSliceFrom2dInnerSlice(pf.model.PDF([]float64{data}), 0)
+	runs := make([]float64, len(pf.RunLengths))
+	for i, r := range pf.RunLengths {
+		runs[i] = float64(r)
+	}
+	H := pf.clock.hazard(pf.hazardFunction(pf.lam, runs))
+	// @ 2. the growth particles and the changepoint particle at the head
+	growth := MulSlice(MulSlice(pf.Res, predprobs), AddConstantSlice(MulConstantSlice(H, -1), 1))
+	cp := SumSlice(MulSlice(MulSlice(pf.Res, predprobs), H))
+	weights := append([]float64{cp}, growth...)
+	if sum := SumSlice(weights); !(sum > 0) || math.IsInf(sum, 1) {
+		// @ no particle explains the value, e.g. its density underflows: restart from the prior
+		weights = make([]float64, len(weights))
+		weights[0] = 1
+	}
+	pf.Res = NormalizeSlice(weights)
+	runLengths := make([]int, 0, len(pf.RunLengths)+1)
+	runLengths = append(runLengths, 0)
+	for _, r := range pf.RunLengths {
+		runLengths = append(runLengths, r+1)
+	}
+	pf.RunLengths = runLengths
+	// @ 3. the model has the same layout: the prior at the head
+	pf.model.UpdateTheta([]float64{data})
+	// @ 4. resample down to N particles
+	if len(pf.Res) > pf.particles {
+		keep, weights := optimalResample(pf.rng, pf.Res, pf.particles)
+		runLengths := make([]int, len(keep))
+		for i, k := range keep {
+			runLengths[i] = pf.RunLengths[k]
+		}
+		pf.RunLengths, pf.Res = runLengths, weights
+		pf.model.Keep(keep)
+	}
+}
+
+// Posterior returns the dense run length posterior, 0 out of the support
+func (pf *ParticleOCPD) Posterior() []float64 {
+	res := make([]float64, pf.RunLengths[len(pf.RunLengths)-1]+1)
+	for i, r := range pf.RunLengths {
+		res[r] = pf.Res[i]
+	}
+	return res
+}
+
+// Update makes ParticleOCPD a Detector
+func (pf *ParticleOCPD) Update(x float64) []Event {
+	n := len(pf.Events)
+	pf.OCPD_Update(x)
+	return append([]Event{}, pf.Events[n:]...)
+}
+
+// State returns the argmax run length and its posterior probability
+func (pf *ParticleOCPD) State() State {
+	s := State{Step: len(pf.Maxes)}
+	if s.Step > 0 {
+		s.RunLength = int(pf.Maxes[s.Step-1])
+		s.Statistic = pf.Res[ArgmaxSlice(pf.Res)]
+	}
+	return s
+}
+
+// Result returns the events of ParticleOCPD as the common result type
+func (pf *ParticleOCPD) Result() Result {
+	return Result{Method: "ParticleOCPD", N: len(pf.Maxes), Events: append([]Event{}, pf.Events...)}
+}
+
+// * optimalResample keeps n of the normalized weights w, Fearnhead & Liu (2007):
+// * c solves sum min(c w, 1) = n, the particles with w >= 1/c are kept as they
+// * are, and the others are resampled by stratified sampling with weight 1/c.
+// * It returns the kept indices, ascending, and their normalized weights.
+func optimalResample(rng *rand.Rand, w []float64, n int) ([]int, []float64) {
+	// @ 1. find c from the weights in descending order, large ones have c w >= 1
+	order := make([]int, len(w))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return w[order[i]] > w[order[j]] })
+	// @    the tail sums from the small end, a subtraction would cancel
+	tail := make([]float64, len(w)+1)
+	for i := len(order) - 1; i >= 0; i-- {
+		tail[i] = tail[i+1] + w[order[i]]
+	}
+	c := float64(n) / tail[0]
+	large := 0
+	for large < n-1 && c*w[order[large]] >= 1 {
+		large++
+		c = float64(n-large) / tail[large]
+	}
+	alpha := 1 / c
+	// @ 2. keep the large ones found in 1, stratified sampling of the small
+	// @    ones, which gives n-large of them up to the rounding, never more
+	isLarge := make([]bool, len(w))
+	for _, i := range order[:large] {
+		isLarge[i] = true
+	}
+	keep := make([]int, 0, n)
+	weights := make([]float64, 0, n)
+	small := 0
+	u := rng.Float64() * alpha
+	for i, v := range w {
+		if isLarge[i] {
+			keep = append(keep, i)
+			weights = append(weights, v)
+			continue
+		}
+		u -= v
+		if u < 0 && small < n-large {
+			keep = append(keep, i)
+			weights = append(weights, alpha)
+			small++
+			u += alpha
+		}
+	}
+	return keep, NormalizeSlice(weights)
+}
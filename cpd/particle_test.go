@@ -0,0 +1,111 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the particle filter against the exact OCPD on ../data/data_output.csv
+func TestParticleOCPD(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	newModel := func() *StudentT_Bayesian_Update {
+		return NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	}
+	exact := NewOCPD(250, ConstantHazardSlice, newModel()).SetConfirmation(25, 0.5)
+	model := newModel()
+	pf := NewParticleOCPD(250, ConstantHazardSlice, model, 50, 1).SetConfirmation(25, 0.5)
+	agree, dist := 0, 0.0
+	for _, x := range data {
+		exact.OCPD_Update(x)
+		pf.OCPD_Update(x)
+		// at most N particles, and the model keeps only their parameter sets
+		assert.LessOrEqual(t, len(pf.RunLengths), 50)
+		assert.Equal(t, len(pf.RunLengths), len(model.PDF([]float64{x})))
+		if pf.Maxes[len(pf.Maxes)-1] == exact.Maxes[len(exact.Maxes)-1] {
+			agree++
+		}
+		// the largest gap between the exact and the particle cdf of the run length
+		post := pf.Posterior()
+		a, b := 0.0, 0.0
+		for r, p := range exact.Res {
+			a += p
+			if r < len(post) {
+				b += post[r]
+			}
+			dist = math.Max(dist, math.Abs(a-b))
+		}
+	}
+	assert.Greater(t, float64(agree)/float64(len(data)), 0.99)
+	assert.Less(t, dist, 0.01)
+	assert.InDelta(t, 1.0, SumSlice(pf.Res), 1e-9)
+	assert.Equal(t, exact.Result().Changepoints(), pf.Result().Changepoints())
+}
+
+// test the optimal resampling keeps n particles and the large weights
+func TestOptimalResample(t *testing.T) {
+	w := NormalizeSlice([]float64{50, 1, 2, 1, 30, 1, 1, 2, 1, 1, 3, 1, 2, 4})
+	keep, weights := optimalResample(rand.New(rand.NewSource(1)), w, 5)
+	assert.InDelta(t, 5, len(keep), 1)
+	assert.Contains(t, keep, 0)
+	assert.Contains(t, keep, 4)
+	assert.InDelta(t, 1.0, SumSlice(weights), 1e-9)
+	assert.IsIncreasing(t, keep)
+}
+
+// test the optimal resampling never keeps more than n particles on skewed weights
+func TestOptimalResampleSkewed(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 200; trial++ {
+		w := make([]float64, 20+rng.Intn(50))
+		for i := range w {
+			w[i] = math.Exp(10 * rng.NormFloat64())
+		}
+		NormalizeSlice(w)
+		n := 2 + rng.Intn(9)
+		keep, weights := optimalResample(rng, w, n)
+		assert.LessOrEqual(t, len(keep), n)
+		assert.Equal(t, len(keep), len(weights))
+		assert.Contains(t, keep, ArgmaxSlice(w))
+		assert.IsIncreasing(t, keep)
+	}
+	// the tiny weights round away in the tail sums, the large ones fill n
+	keep, _ := optimalResample(rng, []float64{0.5, 1e-18, 0.5, 1e-18}, 2)
+	assert.Equal(t, []int{0, 2}, keep)
+}
+
+// test the missing values and the values no particle explains do not break the filter
+func TestParticleOCPDNaN(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")[:200]
+	data[30], data[100] = math.NaN(), math.Inf(1)
+	data[150] = 1e300
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	pf := NewParticleOCPD(250, ConstantHazardSlice, st, 50, 1).SetConfirmation(25, 0.5)
+	events := RunDetector(pf, data)
+	assert.Equal(t, len(data), len(pf.Maxes))
+	assert.InDelta(t, 1.0, SumSlice(pf.Res), 1e-9)
+	// the missing values go on with the segment
+	assert.Equal(t, pf.Maxes[29]+1, pf.Maxes[30])
+	assert.Equal(t, pf.Maxes[99]+1, pf.Maxes[100])
+	assert.Contains(t, pf.Result().Changepoints(), 58)
+	assert.NotEmpty(t, events)
+}
+
+// test a gap keeps the positions of the trend model in step with the run lengths
+func TestParticleOCPDNaNTrend(t *testing.T) {
+	data := SimulateTrend([]int{150}, []float64{0.2}, 0.5, 1).Data
+	for i := 60; i < 65; i++ {
+		data[i] = math.NaN()
+	}
+	model := NewTrend_BU(1, 1, []float64{0, 0}, []float64{100, 1})
+	pf := NewParticleOCPD(250, ConstantHazardSlice, model, 50, 1).SetConfirmation(25, 0.5)
+	for _, x := range data {
+		pf.OCPD_Update(x)
+		assert.Equal(t, pf.RunLengths, model.runs)
+	}
+	// the trend goes on through the gap, no changepoint
+	assert.Empty(t, pf.Result().Changepoints())
+	assert.Equal(t, float64(len(data)), pf.Maxes[len(data)-1])
+}
@@ -0,0 +1,299 @@
+package cpd
+
+import (
+	"math"
+	"sort"
+)
+
+// * Cost is the segment cost for the offline detectors, twice the negative
+// * log likelihood of the segment up to a constant.
+// * Fit precomputes what is needed, then Cost(start, end) is for data[start:end].
+type Cost interface {
+	Fit(data []float64)
+	Cost(start, end int) float64
+	// Params is the number of parameters per segment, used by the penalties
+	Params() int
+}
+
+// * MeanCost is the Gaussian mean shift cost with the variance estimated
+// * once for the whole series from the MAD of the first differences.
+type MeanCost struct {
+	s1, s2 []float64
+	sigma2 float64
+}
+
+func NewMeanCost() *MeanCost { return &MeanCost{} }
+
+func (c *MeanCost) Fit(data []float64) {
+	c.s1, c.s2 = cumSums(data)
+	c.sigma2 = madDiffVariance(data)
+}
+
+func (c *MeanCost) Cost(start, end int) float64 {
+	n := float64(end - start)
+	s1 := c.s1[end] - c.s1[start]
+	s2 := c.s2[end] - c.s2[start]
+	return (s2 - s1*s1/n) / c.sigma2
+}
+
+func (c *MeanCost) Params() int { return 1 }
+
+// * MeanVarCost is the Gaussian likelihood cost with the mean and the variance
+// * of every segment estimated from the segment itself.
+type MeanVarCost struct {
+	s1, s2 []float64
+}
+
+func NewMeanVarCost() *MeanVarCost { return &MeanVarCost{} }
+
+func (c *MeanVarCost) Fit(data []float64) {
+	c.s1, c.s2 = cumSums(data)
+}
+
+func (c *MeanVarCost) Cost(start, end int) float64 {
+	n := float64(end - start)
+	s1 := c.s1[end] - c.s1[start]
+	s2 := c.s2[end] - c.s2[start]
+	// @ floor the variance to keep the constant segments finite
+	sigma2 := math.Max((s2-s1*s1/n)/n, 1e-8)
+	return n * (math.Log(2*math.Pi*sigma2) + 1)
+}
+
+func (c *MeanVarCost) Params() int { return 2 }
+
+// * PoissonCost is the cost for count data with a rate change.
+type PoissonCost struct {
+	s1 []float64
+}
+
+func NewPoissonCost() *PoissonCost { return &PoissonCost{} }
+
+func (c *PoissonCost) Fit(data []float64) {
+	for _, x := range data {
+		if x < 0 {
+			panic("Poisson cost needs non-negative data")
+		}
+	}
+	c.s1, _ = cumSums(data)
+}
+
+func (c *PoissonCost) Cost(start, end int) float64 {
+	n := float64(end - start)
+	s1 := c.s1[end] - c.s1[start]
+	if s1 == 0 {
+		return 0
+	}
+	return 2 * (s1 - s1*math.Log(s1/n))
+}
+
+func (c *PoissonCost) Params() int { return 1 }
+
+// * ECDFCost is the nonparametric cost of Haynes, Fearnhead and Eckley (2017):
+// * the segment likelihood of the empirical CDF at K quantiles of the data.
+type ECDFCost struct {
+	k      int
+	counts [][]float64
+	scale  float64
+}
+
+// NewECDFCost returns the empirical CDF cost with k quantiles,
+// k <= 0 means the default ceil(4 log n).
+func NewECDFCost(k int) *ECDFCost { return &ECDFCost{k: k} }
+
+func (c *ECDFCost) Fit(data []float64) {
+	n := len(data)
+	k := c.k
+	if k <= 0 {
+		k = int(math.Ceil(4 * math.Log(float64(n))))
+	}
+	sorted := append([]float64{}, data...)
+	sort.Float64s(sorted)
+	// @ the quantiles are denser in the tails
+	cc := -math.Log(float64(2*n - 1))
+	c.counts = make([][]float64, k)
+	for i := 0; i < k; i++ {
+		p := 1 / (1 + float64(2*n-1)*math.Exp(cc/float64(k)*float64(2*i+1)))
+		q := sorted[int(math.Min(float64(n-1), math.Floor(float64(n-1)*p)))]
+		// @ counts[i][j] is the number of data[:j] below q, half for the ties
+		cum := make([]float64, n+1)
+		for j, x := range data {
+			cum[j+1] = cum[j]
+			if x < q {
+				cum[j+1] += 1
+			} else if x == q {
+				cum[j+1] += 0.5
+			}
+		}
+		c.counts[i] = cum
+	}
+	c.scale = -2 * cc / float64(k)
+}
+
+func (c *ECDFCost) Cost(start, end int) float64 {
+	n := float64(end - start)
+	res := 0.0
+	for _, cum := range c.counts {
+		f := (cum[end] - cum[start]) / n
+		if f <= 0 || f >= 1 {
+			continue
+		}
+		res -= n * (f*math.Log(f) + (1-f)*math.Log(1-f))
+	}
+	return c.scale * res
+}
+
+func (c *ECDFCost) Params() int { return 1 }
+
+// * PenaltyKind selects how the penalty per changepoint is computed.
+type PenaltyKind int
+
+const (
+	// BIC is params * log(n)
+	BIC PenaltyKind = iota
+	// MBIC is (params + 2) * log(n) plus log of the segment lengths
+	MBIC
+	// ManualPenalty uses Penalty.Value as it is
+	ManualPenalty
+)
+
+// * Penalty is the price of one more changepoint in the offline detectors.
+type Penalty struct {
+	Kind  PenaltyKind
+	Value float64
+}
+
+// value returns the penalty per changepoint for a series of length n
+func (p Penalty) value(n, params int) float64 {
+	switch p.Kind {
+	case BIC:
+		return float64(params) * math.Log(float64(n))
+	case MBIC:
+		return float64(params+2) * math.Log(float64(n))
+	default:
+		return p.Value
+	}
+}
+
+// * PELT is the Pruned Exact Linear Time search of Killick et al. (2012).
+// * It returns the exact minimum of the penalized cost over all the
+// * segmentations with segments at least minSize long.
+// * The Score of each event is its cost reduction.
+func PELT(data []float64, cost Cost, penalty Penalty, minSize int) Result {
+	n := len(data)
+	if minSize < 1 {
+		minSize = 1
+	}
+	if n < 2*minSize {
+		return NewOfflineResult("PELT", n, nil, nil)
+	}
+	cost.Fit(data)
+	pen := penalty.value(n, cost.Params())
+	segCost := func(start, end int) float64 {
+		c := cost.Cost(start, end)
+		if penalty.Kind == MBIC {
+			c += math.Log(float64(end - start))
+		}
+		return c
+	}
+	// @ the pruning needs C(s,u) + C(u,t) <= C(s,t) + slack, the log length
+	// @ term of MBIC breaks it by up to log(n)
+	slack := 0.0
+	if penalty.Kind == MBIC {
+		slack = math.Log(float64(n))
+	}
+
+	// @ F[t] is the optimal cost of data[:t], last[t] its last changepoint
+	F := make([]float64, n+1)
+	last := make([]int, n+1)
+	F[0] = -pen
+	candidates := []int{0}
+	for t := 1; t <= n; t++ {
+		F[t] = math.Inf(1)
+		if t < minSize {
+			continue
+		}
+		costs := make([]float64, len(candidates))
+		for i, s := range candidates {
+			costs[i] = math.Inf(1)
+			if t-s < minSize {
+				continue
+			}
+			costs[i] = F[s] + segCost(s, t)
+			if costs[i]+pen < F[t] {
+				F[t] = costs[i] + pen
+				last[t] = s
+			}
+		}
+		// @ prune the candidates which can never be optimal again
+		kept := candidates[:0]
+		for i, s := range candidates {
+			if t-s < minSize || costs[i]-slack <= F[t] {
+				kept = append(kept, s)
+			}
+		}
+		candidates = kept
+		if t+minSize <= n {
+			candidates = append(candidates, t)
+		}
+	}
+
+	// @ backtrack the changepoints
+	var cps []int
+	for t := last[n]; t > 0; t = last[t] {
+		cps = append([]int{t}, cps...)
+	}
+	return NewOfflineResult("PELT", n, cps, costReductions(cost, cps, n))
+}
+
+// costReductions is C(prev, next) - C(prev, cp) - C(cp, next) for each changepoint
+func costReductions(cost Cost, cps []int, n int) []float64 {
+	bounds := append(append([]int{0}, cps...), n)
+	scores := make([]float64, len(cps))
+	for i := range cps {
+		scores[i] = cost.Cost(bounds[i], bounds[i+2]) - cost.Cost(bounds[i], bounds[i+1]) - cost.Cost(bounds[i+1], bounds[i+2])
+	}
+	return scores
+}
+
+// cumSums returns the cumulative sums of x and x^2 with a leading 0
+func cumSums(data []float64) ([]float64, []float64) {
+	s1 := make([]float64, len(data)+1)
+	s2 := make([]float64, len(data)+1)
+	for i, x := range data {
+		s1[i+1] = s1[i] + x
+		s2[i+1] = s2[i] + x*x
+	}
+	return s1, s2
+}
+
+// madDiffVariance estimates the noise variance from the MAD of the first
+// differences, which is not affected by the mean shifts
+func madDiffVariance(data []float64) float64 {
+	if len(data) < 3 {
+		return 1
+	}
+	diffs := make([]float64, len(data)-1)
+	for i := range diffs {
+		diffs[i] = data[i+1] - data[i]
+	}
+	med := median(diffs)
+	for i := range diffs {
+		diffs[i] = math.Abs(diffs[i] - med)
+	}
+	sigma := 1.4826 * median(diffs) / math.Sqrt2
+	if sigma <= 0 {
+		return 1
+	}
+	return sigma * sigma
+}
+
+// median returns the median of the data without changing it
+func median(data []float64) float64 {
+	sorted := append([]float64{}, data...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
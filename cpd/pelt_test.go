@@ -0,0 +1,116 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the PELT finds the partition [58, 74, 117, 153, 137, 129, 188]
+func TestPELT(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+
+	res := PELT(data, NewMeanVarCost(), Penalty{Kind: BIC}, 5)
+	assert.Equal(t, "PELT", res.Method)
+	assert.Equal(t, len(data), res.N)
+	assert.Equal(t, truth, res.Changepoints())
+	for _, e := range res.Events {
+		assert.Equal(t, Changepoint, e.Kind)
+		assert.Greater(t, e.Score, 0.0)
+	}
+
+	// the nonparametric cost finds the same partition within a few steps
+	res = PELT(data, NewECDFCost(0), Penalty{Kind: ManualPenalty, Value: 20}, 5)
+	assert.Equal(t, len(truth), len(res.Changepoints()))
+	for i, cp := range res.Changepoints() {
+		assert.InDelta(t, truth[i], cp, 3)
+	}
+
+	// a huge manual penalty leaves no changepoint
+	res = PELT(data, NewMeanVarCost(), Penalty{Kind: ManualPenalty, Value: 1e9}, 2)
+	assert.Empty(t, res.Changepoints())
+}
+
+// test the Poisson cost finds a rate change in count data
+func TestPELTPoisson(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	var data []float64
+	for i := 0; i < 300; i++ {
+		lam := 2.0
+		if i >= 180 {
+			lam = 6.0
+		}
+		// @ Knuth's sampler is enough for small rates
+		k, p := 0.0, rng.Float64()
+		for limit := math.Exp(-lam); p > limit; k++ {
+			p *= rng.Float64()
+		}
+		data = append(data, k)
+	}
+	res := PELT(data, NewPoissonCost(), Penalty{Kind: MBIC}, 5)
+	assert.Equal(t, 1, len(res.Changepoints()))
+	assert.InDelta(t, 180, res.Changepoints()[0], 5)
+}
+
+// test PELT with MBIC finds the optimum of the unpruned search
+func TestPELTMBICPruning(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		data := make([]float64, 10+rng.Intn(80))
+		for i := range data {
+			data[i] = rng.NormFloat64() * (1 + float64(3*i/len(data)))
+			if rng.Float64() < 0.05 {
+				data[i] += 3
+			}
+		}
+		for _, cost := range []Cost{NewMeanCost(), NewMeanVarCost()} {
+			res := PELT(data, cost, Penalty{Kind: MBIC}, 1)
+			want, best := optimalPartitioning(data, cost, 1)
+			assert.InDelta(t, best, mbicCost(data, cost, res.Changepoints()), 1e-9)
+			assert.Equal(t, len(want), len(res.Changepoints()))
+		}
+	}
+}
+
+// optimalPartitioning is the search of PELT without the pruning, with the MBIC penalty
+func optimalPartitioning(data []float64, cost Cost, minSize int) ([]int, float64) {
+	n := len(data)
+	cost.Fit(data)
+	pen := Penalty{Kind: MBIC}.value(n, cost.Params())
+	F := make([]float64, n+1)
+	last := make([]int, n+1)
+	F[0] = -pen
+	for t := 1; t <= n; t++ {
+		F[t] = math.Inf(1)
+		for s := 0; s+minSize <= t; s++ {
+			if s > 0 && s < minSize {
+				continue
+			}
+			c := F[s] + cost.Cost(s, t) + math.Log(float64(t-s)) + pen
+			if c < F[t] {
+				F[t], last[t] = c, s
+			}
+		}
+	}
+	var cps []int
+	for t := last[n]; t > 0; t = last[t] {
+		cps = append([]int{t}, cps...)
+	}
+	return cps, F[n]
+}
+
+// mbicCost is the penalized MBIC cost of a segmentation
+func mbicCost(data []float64, cost Cost, cps []int) float64 {
+	n := len(data)
+	cost.Fit(data)
+	pen := Penalty{Kind: MBIC}.value(n, cost.Params())
+	bounds := append(append([]int{0}, cps...), n)
+	res := float64(len(cps)) * pen
+	for i := 0; i+1 < len(bounds); i++ {
+		res += cost.Cost(bounds[i], bounds[i+1]) + math.Log(float64(bounds[i+1]-bounds[i]))
+	}
+	return res
+}
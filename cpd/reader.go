@@ -0,0 +1,288 @@
+package cpd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// * CSVOptions configures ReadCSV. The zero value reads all the columns of
+// * a comma separated file without header.
+type CSVOptions struct {
+	// Delimiter is the field separator, ',' if zero
+	Delimiter rune
+	// Comment starts the comment lines, none if zero
+	Comment rune
+	// Header is true when the first row names the columns
+	Header bool
+	// Columns selects the columns by name, it needs Header
+	Columns []string
+	// Indices selects the columns by index, from 0
+	Indices []int
+	// Missing are the cells read as NaN, e.g. "" or "NA"
+	Missing []string
+	// Time is the timestamp column, by name or by index, e.g. "0", and
+	// TimeLayout its format, see ParseTime. It is not one of the series.
+	Time       string
+	TimeLayout string
+}
+
+// * NamedSeries is one column of a csv file. Name is the header of the
+// * column, or its index without header.
+type NamedSeries struct {
+	Name string
+	Data []float64
+}
+
+// * TimeSeries is the series of a csv file with the timestamps of the rows.
+type TimeSeries struct {
+	Time   []time.Time
+	Series []NamedSeries
+}
+
+// * CSVError is a cell which could not be read. Line and Column start at 1,
+// * Column is the field of the row, Name the name of its column.
+type CSVError struct {
+	File         string
+	Line, Column int
+	Name         string
+	Value        string
+	Err          error
+}
+
+func (e *CSVError) Error() string {
+	pos := fmt.Sprintf("line %d, column %d", e.Line, e.Column)
+	if e.File != "" {
+		pos = fmt.Sprintf("%s: %s", e.File, pos)
+	}
+	if e.Name != "" {
+		pos += fmt.Sprintf(" (%s)", e.Name)
+	}
+	return fmt.Sprintf("%s: %q: %v", pos, e.Value, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// ReadCSV reads the selected columns of a csv file, see ParseCSV
+func ReadCSV(filename string, opts CSVOptions) ([]NamedSeries, error) {
+	ts, err := readTable(filename, opts)
+	return ts.Series, err
+}
+
+// ReadTimeSeries reads the timestamps and the selected columns of a csv file,
+// see ParseTimeSeries
+func ReadTimeSeries(filename string, opts CSVOptions) (TimeSeries, error) {
+	if opts.Time == "" {
+		return TimeSeries{}, fmt.Errorf("%s: no time column", filename)
+	}
+	return readTable(filename, opts)
+}
+
+func readTable(filename string, opts CSVOptions) (TimeSeries, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	defer file.Close()
+	res, err := parseTable(file, opts)
+	var cellErr *CSVError
+	if errors.As(err, &cellErr) {
+		cellErr.File = filename
+	} else if err != nil {
+		err = fmt.Errorf("%s: %w", filename, err)
+	}
+	return res, err
+}
+
+// ParseCSV reads the selected columns, in the order of the selection, or all
+// of them but the time column. The fields may be quoted, blank lines are
+// skipped. A cell which is not a number, nor a Missing one, is a *CSVError
+// with its line and column.
+func ParseCSV(r io.Reader, opts CSVOptions) ([]NamedSeries, error) {
+	ts, err := parseTable(r, opts)
+	return ts.Series, err
+}
+
+// ParseTimeSeries is ParseCSV with the timestamps of the Time column, a
+// timestamp which does not parse is a *CSVError as well
+func ParseTimeSeries(r io.Reader, opts CSVOptions) (TimeSeries, error) {
+	if opts.Time == "" {
+		return TimeSeries{}, fmt.Errorf("no time column")
+	}
+	return parseTable(r, opts)
+}
+
+func parseTable(r io.Reader, opts CSVOptions) (TimeSeries, error) {
+	if len(opts.Columns) > 0 && len(opts.Indices) > 0 {
+		return TimeSeries{}, fmt.Errorf("select the columns by name or by index, not both")
+	}
+	if len(opts.Columns) > 0 && !opts.Header {
+		return TimeSeries{}, fmt.Errorf("the columns by name need a header")
+	}
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.Comment = opts.Comment
+	// @ the rows may differ in length, only the selected columns must be there
+	reader.FieldsPerRecord = -1
+
+	// @ 1. the header names the columns
+	var header []string
+	if opts.Header {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return TimeSeries{}, fmt.Errorf("no header")
+		}
+		if err != nil {
+			return TimeSeries{}, err
+		}
+		header = append([]string{}, row...)
+		for i := range header {
+			header[i] = strings.TrimSpace(header[i])
+		}
+		// @ the byte order mark of the spreadsheets
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	// @ 2. the indices of the selection and of the time column
+	indices := opts.Indices
+	for _, name := range opts.Columns {
+		i := indexOf(header, name)
+		if i < 0 {
+			return TimeSeries{}, fmt.Errorf("no column %q in the header %v", name, header)
+		}
+		indices = append(indices, i)
+	}
+	timeIndex := -1
+	if opts.Time != "" {
+		timeIndex = indexOf(header, opts.Time)
+		if timeIndex < 0 {
+			i, err := strconv.Atoi(opts.Time)
+			if err != nil {
+				return TimeSeries{}, fmt.Errorf("no time column %q in the header %v", opts.Time, header)
+			}
+			timeIndex = i
+		}
+	}
+	outside := func(i int) bool { return i < 0 || (header != nil && i >= len(header)) }
+	for _, i := range indices {
+		if outside(i) {
+			return TimeSeries{}, fmt.Errorf("no column %d", i)
+		}
+	}
+	if opts.Time != "" && outside(timeIndex) {
+		return TimeSeries{}, fmt.Errorf("no time column %d", timeIndex)
+	}
+	name := func(i int) string {
+		if header != nil {
+			return header[i]
+		}
+		return strconv.Itoa(i)
+	}
+
+	// @ 3. the rows
+	var res TimeSeries
+	started := false
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return TimeSeries{}, err
+		}
+		line, _ := reader.FieldPos(0)
+		if !started {
+			// @ without selection, all the columns of the header or of the first row
+			if len(indices) == 0 {
+				n := len(row)
+				if header != nil {
+					n = len(header)
+				}
+				for i := 0; i < n; i++ {
+					if i != timeIndex {
+						indices = append(indices, i)
+					}
+				}
+			}
+			res.Series = newSeries(indices, name)
+			started = true
+		}
+		missing := func(i int) error {
+			return &CSVError{Line: line, Column: i + 1, Name: name(i), Err: fmt.Errorf("missing column, the row has %d", len(row))}
+		}
+		for _, i := range indices {
+			if i >= len(row) {
+				return TimeSeries{}, missing(i)
+			}
+		}
+		if timeIndex >= len(row) {
+			return TimeSeries{}, missing(timeIndex)
+		}
+		if timeIndex >= 0 {
+			t, err := ParseTime(row[timeIndex], opts.TimeLayout)
+			if err != nil {
+				line, _ := reader.FieldPos(timeIndex)
+				return TimeSeries{}, &CSVError{Line: line, Column: timeIndex + 1, Name: name(timeIndex), Value: row[timeIndex], Err: err}
+			}
+			res.Time = append(res.Time, t)
+		}
+		for j, i := range indices {
+			val, err := parseCell(row[i], opts.Missing)
+			if err != nil {
+				line, _ := reader.FieldPos(i)
+				return TimeSeries{}, &CSVError{Line: line, Column: i + 1, Name: name(i), Value: row[i], Err: err}
+			}
+			res.Series[j].Data = append(res.Series[j].Data, val)
+		}
+	}
+	// @ an empty file still has the selected series
+	if !started {
+		res.Series = newSeries(indices, name)
+	}
+	return res, nil
+}
+
+// newSeries returns the empty series of the columns
+func newSeries(indices []int, name func(i int) string) []NamedSeries {
+	res := make([]NamedSeries, len(indices))
+	for j, i := range indices {
+		res[j].Name = name(i)
+	}
+	return res
+}
+
+// parseCell reads a number, NaN for the missing cells
+func parseCell(cell string, missing []string) (float64, error) {
+	cell = strings.TrimSpace(cell)
+	if indexOf(missing, cell) >= 0 {
+		return math.NaN(), nil
+	}
+	val, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return 0, err
+	}
+	return val, nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,158 @@
+package cpd
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSV(t *testing.T) {
+	text := "# exported from the desk\n" +
+		"\ufefftime;\"price, close\";volume\n" +
+		"1;10.5;100\n" +
+		"\n" +
+		"# a comment between the rows\n" +
+		"2;\"11\";NA\n" +
+		"3; 12.25 ;300\n"
+	opts := CSVOptions{Delimiter: ';', Comment: '#', Header: true, Missing: []string{"NA"}}
+
+	// all the columns, named by the header
+	series, err := ParseCSV(strings.NewReader(text), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(series))
+	assert.Equal(t, "time", series[0].Name)
+	assert.Equal(t, "price, close", series[1].Name)
+	assert.Equal(t, []float64{10.5, 11, 12.25}, series[1].Data)
+	assert.Equal(t, 100.0, series[2].Data[0])
+	assert.True(t, math.IsNaN(series[2].Data[1]))
+
+	// by name, in the order of the selection
+	opts.Columns = []string{"volume", "time"}
+	series, err = ParseCSV(strings.NewReader(text), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "volume", series[0].Name)
+	assert.Equal(t, []float64{1, 2, 3}, series[1].Data)
+
+	// by index
+	opts.Columns, opts.Indices = nil, []int{1}
+	series, err = ParseCSV(strings.NewReader(text), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(series))
+	assert.Equal(t, "price, close", series[0].Name)
+
+	// without header the names are the indices
+	series, err = ParseCSV(strings.NewReader("1,2\n3,4\n"), CSVOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []NamedSeries{{Name: "0", Data: []float64{1, 3}}, {Name: "1", Data: []float64{2, 4}}}, series)
+	series, err = ParseCSV(strings.NewReader("a,b\n"), CSVOptions{Header: true, Columns: []string{"b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []NamedSeries{{Name: "b"}}, series)
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	text := "a,b\n1,2\n3,x\n"
+	_, err := ParseCSV(strings.NewReader(text), CSVOptions{Header: true})
+	var cellErr *CSVError
+	assert.True(t, errors.As(err, &cellErr))
+	assert.Equal(t, 3, cellErr.Line)
+	assert.Equal(t, 2, cellErr.Column)
+	assert.Equal(t, "b", cellErr.Name)
+	assert.Equal(t, "x", cellErr.Value)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	assert.Equal(t, `line 3, column 2 (b): "x": invalid syntax`, err.Error())
+
+	// the lines count the comments and the blank lines
+	_, err = ParseCSV(strings.NewReader("# c\n1\n\n2,5\n"), CSVOptions{Comment: '#', Indices: []int{1}})
+	assert.True(t, errors.As(err, &cellErr))
+	assert.Equal(t, 2, cellErr.Line)
+	assert.Contains(t, err.Error(), "missing column")
+
+	// the empty cells are errors unless they are missing
+	_, err = ParseCSV(strings.NewReader("1\n\"\"\n"), CSVOptions{})
+	assert.ErrorContains(t, err, "line 2, column 1")
+	series, err := ParseCSV(strings.NewReader("1\n\"\"\n"), CSVOptions{Missing: []string{""}})
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(series[0].Data[1]))
+
+	// the quotes
+	_, err = ParseCSV(strings.NewReader("1\n\"2\n"), CSVOptions{})
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = ParseCSV(strings.NewReader(text), CSVOptions{Header: true, Columns: []string{"c"}})
+	assert.ErrorContains(t, err, `no column "c"`)
+	_, err = ParseCSV(strings.NewReader(text), CSVOptions{Columns: []string{"a"}})
+	assert.Error(t, err)
+	_, err = ParseCSV(strings.NewReader(text), CSVOptions{Header: true, Indices: []int{2}})
+	assert.Error(t, err)
+	_, err = ParseCSV(strings.NewReader(""), CSVOptions{Header: true})
+	assert.Error(t, err)
+}
+
+func TestReadCSV(t *testing.T) {
+	series, err := ReadCSV("../data/data_output.csv", CSVOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(series))
+	assert.Equal(t, 856, len(series[0].Data))
+	assert.Equal(t, series[0].Data, MustReadData("../data/data_output.csv"))
+
+	path := filepath.Join(t.TempDir(), "bad.csv")
+	assert.Nil(t, os.WriteFile(path, []byte("1\n2\nthree\n"), 0644))
+	_, err = ReadCSV(path, CSVOptions{})
+	assert.ErrorContains(t, err, path+": line 3, column 1")
+	_, err = ReadCSV(filepath.Join(t.TempDir(), "none.csv"), CSVOptions{})
+	assert.Error(t, err)
+
+	// ReadData returns the errors, MustReadData panics
+	_, err = ReadData(path)
+	var csvErr *CSVError
+	assert.ErrorAs(t, err, &csvErr)
+	assert.Panics(t, func() { MustReadData(path) })
+}
+
+func TestParseTimeSeries(t *testing.T) {
+	text := "value,timestamp,volume\n" +
+		"1.5,2024-03-01T09:30:00Z,10\n" +
+		"2.5,2024-03-01T09:31:00.5Z,20\n"
+	ts, err := ParseTimeSeries(strings.NewReader(text), CSVOptions{Header: true, Time: "timestamp"})
+	assert.Nil(t, err)
+	// the time column is not a series
+	assert.Equal(t, []NamedSeries{{Name: "value", Data: []float64{1.5, 2.5}}, {Name: "volume", Data: []float64{10, 20}}}, ts.Series)
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 31, 0, 5e8, time.UTC), ts.Time[1])
+
+	// by index without header, in milliseconds
+	ts, err = ParseTimeSeries(strings.NewReader("1709285400000,1\n1709285460000,2\n"), CSVOptions{Time: "0", TimeLayout: "unixms"})
+	assert.Nil(t, err)
+	assert.Equal(t, []NamedSeries{{Name: "1", Data: []float64{1, 2}}}, ts.Series)
+	assert.Equal(t, time.Minute, ts.Time[1].Sub(ts.Time[0]))
+
+	// a bad timestamp is located as a bad value
+	_, err = ParseTimeSeries(strings.NewReader(text+"3,yesterday,30\n"), CSVOptions{Header: true, Time: "timestamp"})
+	var cellErr *CSVError
+	assert.True(t, errors.As(err, &cellErr))
+	assert.Equal(t, 4, cellErr.Line)
+	assert.Equal(t, 2, cellErr.Column)
+	assert.Equal(t, "timestamp", cellErr.Name)
+
+	_, err = ParseTimeSeries(strings.NewReader(text), CSVOptions{Header: true})
+	assert.ErrorContains(t, err, "no time column")
+	_, err = ParseTimeSeries(strings.NewReader(text), CSVOptions{Header: true, Time: "date"})
+	assert.ErrorContains(t, err, `no time column "date"`)
+	_, err = ParseTimeSeries(strings.NewReader("1\n"), CSVOptions{Time: "1"})
+	assert.ErrorContains(t, err, "line 1, column 2")
+
+	path := filepath.Join(t.TempDir(), "ticks.csv")
+	assert.Nil(t, os.WriteFile(path, []byte(text), 0644))
+	ts, err = ReadTimeSeries(path, CSVOptions{Header: true, Time: "timestamp", Columns: []string{"volume"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ts.Time))
+	assert.Equal(t, "volume", ts.Series[0].Name)
+	_, err = ReadTimeSeries(path, CSVOptions{Header: true})
+	assert.Error(t, err)
+}
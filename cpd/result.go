@@ -0,0 +1,54 @@
+package cpd
+
+import "sort"
+
+// * Result is the common output of all the detectors in the package,
+// * online or offline, so they can be compared with each other.
+type Result struct {
+	// Method is the name of the detector
+	Method string
+	// N is the length of the series
+	N int
+	// Events are sorted by Index
+	Events []Event
+	// Segments are filled by the detectors which estimate the segments too
+	Segments []Segment
+}
+
+// * Segment is data[Start:End] between two changepoints.
+type Segment struct {
+	Start, End int
+	// From and To are the edges of the segment on the time axis, if any
+	From, To float64
+	// Value is the estimate of the segment, e.g. the mean or the rate
+	Value float64
+	// Params are the model parameters of the segment, if any
+	Params []float64
+}
+
+// NewOfflineResult builds a Result from the changepoint indices of an offline
+// detector: all of them are decided at the last step of the series.
+// scores may be nil, otherwise it has one entry per changepoint.
+func NewOfflineResult(method string, n int, cps []int, scores []float64) Result {
+	res := Result{Method: method, N: n, Events: make([]Event, 0, len(cps))}
+	for i, cp := range cps {
+		e := Event{Kind: Changepoint, Index: cp, Detected: n - 1, Confirmed: n - 1, Latency: n - 1 - cp}
+		if scores != nil {
+			e.Score = scores[i]
+		}
+		res.Events = append(res.Events, e)
+	}
+	sort.Slice(res.Events, func(i, j int) bool { return res.Events[i].Index < res.Events[j].Index })
+	return res
+}
+
+// Changepoints returns the sorted indices of the Changepoint events
+func (r Result) Changepoints() []int {
+	cps := make([]int, 0, len(r.Events))
+	for _, e := range r.Events {
+		if e.Kind == Changepoint {
+			cps = append(cps, e.Index)
+		}
+	}
+	return cps
+}
@@ -0,0 +1,108 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// * Sample draws count segmentations from the exact posterior of OfflineBOCPD
+// * by backward sampling: the start of the last segment first,
+// * P(s) ~ F(s) P(x_s..x_n-1) G(n-s), then the start of the segment before it,
+// * P(s' | s) ~ F(s') P(x_s'..x_s-1) g(s-s'), until the start of the series.
+// * Each segmentation is the sorted changepoint indices.
+func (ob *OfflineBOCPD) Sample(count int, seed int64) [][]int {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([][]int, count)
+	for i := range samples {
+		var cps []int
+		end := ob.n
+		for end > 0 {
+			logw := make([]float64, end)
+			for s := 0; s < end; s++ {
+				logw[s] = ob.logF[s] + ob.segLL[s][end-1-s]
+				if end == ob.n {
+					logw[s] += ob.logG[end-s]
+				} else {
+					logw[s] += ob.logg(end - s)
+				}
+			}
+			s := sampleLog(rng, logw)
+			if s > 0 {
+				cps = append(cps, s)
+			}
+			end = s
+		}
+		sort.Ints(cps)
+		samples[i] = cps
+	}
+	return samples
+}
+
+// sampleLog draws an index with the probabilities proportional to exp(logw)
+func sampleLog(rng *rand.Rand, logw []float64) int {
+	norm := logSumExp(logw)
+	u := rng.Float64()
+	acc := 0.0
+	for i, w := range logw {
+		acc += math.Exp(w - norm)
+		if u < acc {
+			return i
+		}
+	}
+	return len(logw) - 1
+}
+
+// * CredibleInterval is the equal tailed interval of one changepoint location.
+type CredibleInterval struct {
+	Median, Lower, Upper int
+}
+
+// * SegmentationSummary summarizes the sampled segmentations.
+type SegmentationSummary struct {
+	// CountProbs[k] is the posterior probability of k changepoints
+	CountProbs []float64
+	// ModalCount is the most probable number of changepoints
+	ModalCount int
+	// Intervals are the credible intervals of the changepoints, one for each
+	// of the ModalCount changepoints, from the samples with ModalCount changepoints
+	Intervals []CredibleInterval
+}
+
+// SummarizeSamples returns the posterior of the number of changepoints and
+// the credible intervals at the level, e.g. 0.95, of the changepoint locations
+func SummarizeSamples(samples [][]int, level float64) SegmentationSummary {
+	var summary SegmentationSummary
+	if len(samples) == 0 {
+		return summary
+	}
+	for _, s := range samples {
+		for len(summary.CountProbs) <= len(s) {
+			summary.CountProbs = append(summary.CountProbs, 0)
+		}
+		summary.CountProbs[len(s)] += 1 / float64(len(samples))
+	}
+	summary.ModalCount = ArgmaxSlice(summary.CountProbs)
+	// @ the i-th changepoint of the samples with the modal count
+	locations := make([][]int, summary.ModalCount)
+	for _, s := range samples {
+		if len(s) == summary.ModalCount {
+			for i, cp := range s {
+				locations[i] = append(locations[i], cp)
+			}
+		}
+	}
+	tail := (1 - level) / 2
+	for _, loc := range locations {
+		sort.Ints(loc)
+		quantile := func(q float64) int {
+			return loc[int(math.Min(float64(len(loc)-1), math.Floor(q*float64(len(loc)))))]
+		}
+		summary.Intervals = append(summary.Intervals, CredibleInterval{
+			Median: quantile(0.5),
+			Lower:  quantile(tail),
+			Upper:  quantile(1 - tail),
+		})
+	}
+	return summary
+}
@@ -0,0 +1,42 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the sampled segmentations of ../data/data_output.csv
+func TestOfflineBOCPDSample(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+	st := NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})
+	ob := NewOfflineBOCPD(data, 250, ConstantHazardSlice, st)
+
+	samples := ob.Sample(500, 1)
+	assert.Equal(t, 500, len(samples))
+	// the same seed gives the same samples
+	assert.Equal(t, samples, ob.Sample(500, 1))
+
+	// the sampled frequencies follow the smoothed probabilities
+	probs := ob.ChangepointProbabilities()
+	freq := make([]float64, len(data))
+	for _, s := range samples {
+		for _, cp := range s {
+			freq[cp] += 1.0 / 500
+		}
+	}
+	assert.InDelta(t, probs[132], freq[132], 0.07)
+	assert.InDelta(t, SumSlice(probs[129:135]), SumSlice(freq[129:135]), 0.05)
+
+	summary := SummarizeSamples(samples, 0.95)
+	assert.InDelta(t, 1.0, SumSlice(summary.CountProbs), 1e-9)
+	assert.Equal(t, len(truth), summary.ModalCount)
+	for i, iv := range summary.Intervals {
+		assert.LessOrEqual(t, iv.Lower, truth[i])
+		assert.GreaterOrEqual(t, iv.Upper, truth[i])
+		assert.LessOrEqual(t, iv.Lower, iv.Median)
+		assert.LessOrEqual(t, iv.Median, iv.Upper)
+	}
+	assert.Equal(t, CredibleInterval{58, 58, 58}, summary.Intervals[0])
+}
@@ -0,0 +1,341 @@
+package cpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// * ScenarioSpec describes a synthetic series as data, in JSON or YAML:
+// *
+// *	seed: 100
+// *	segments:
+// *	  - distribution: normal
+// *	    repeat: 5
+// *	    length: {dist: uniform, min: 50, max: 1000}
+// *	    params:
+// *	      mean: {dist: normal, mean: 0, std: 10}
+// *	      std: {dist: halfnormal, std: 1}
+// *	    anomalies: {outliers: {rate: 0.01, scale: 20}, missing: 0.02}
+// *	seasonality: {period: 50, amplitude: 2}
+// *
+// * A uniform length is an integer in [min, max): max is exclusive, both
+// * bounds are integers and min is at least 1. The other lengths are rounded,
+// * at least 1.
+// * All the draws come from one source seeded by seed: first the lengths of
+// * all the segments, then segment by segment its parameters, its data with
+// * the noise and its anomalies, so the same file gives the same series.
+type ScenarioSpec struct {
+	Name        string        `json:"name" yaml:"name"`
+	Seed        int64         `json:"seed" yaml:"seed"`
+	Segments    []SegmentSpec `json:"segments" yaml:"segments"`
+	Seasonality *SeasonSpec   `json:"seasonality,omitempty" yaml:"seasonality,omitempty"`
+}
+
+// * SegmentSpec is one segment, or repeat segments drawn the same way.
+// * The distributions and their parameters, in the order they are drawn:
+// * normal (mean, std), poisson (rate), bernoulli (p), ar (phi, std) and
+// * trend (slope, std). ar and trend carry their state over the changes.
+type SegmentSpec struct {
+	Distribution string           `json:"distribution" yaml:"distribution"`
+	Params       map[string]Value `json:"params" yaml:"params"`
+	Length       Value            `json:"length" yaml:"length"`
+	// Repeat is the number of segments, 1 if not set
+	Repeat int `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+	// Noise is the std of the normal noise added to the data
+	Noise     float64      `json:"noise,omitempty" yaml:"noise,omitempty"`
+	Anomalies *AnomalySpec `json:"anomalies,omitempty" yaml:"anomalies,omitempty"`
+}
+
+// * AnomalySpec injects outliers and missing values into a segment.
+type AnomalySpec struct {
+	Outliers *OutlierSpec `json:"outliers,omitempty" yaml:"outliers,omitempty"`
+	// Missing is the rate of the NaNs
+	Missing float64 `json:"missing,omitempty" yaml:"missing,omitempty"`
+}
+
+// * OutlierSpec is the rate and the size of the spikes, as Simulation.AddOutliers.
+type OutlierSpec struct {
+	Rate  float64 `json:"rate" yaml:"rate"`
+	Scale float64 `json:"scale" yaml:"scale"`
+}
+
+// * SeasonSpec is the seasonal component, as Simulation.AddSeasonality.
+type SeasonSpec struct {
+	Period    int     `json:"period" yaml:"period"`
+	Amplitude float64 `json:"amplitude" yaml:"amplitude"`
+}
+
+// * Value is a number of a scenario: fixed, e.g. 3, or drawn from a
+// * distribution: {dist: normal, mean, std}, {dist: halfnormal, std} or
+// * {dist: uniform, min, max}. A uniform length is an integer in [min, max).
+type Value struct {
+	Fixed float64 `json:"-" yaml:"-"`
+	Dist  string  `json:"dist" yaml:"dist"`
+	Mean  float64 `json:"mean,omitempty" yaml:"mean,omitempty"`
+	Std   float64 `json:"std,omitempty" yaml:"std,omitempty"`
+	Min   float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max   float64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// valueFields is Value without the methods, to decode the distributions
+type valueFields struct {
+	Dist string  `json:"dist" yaml:"dist"`
+	Mean float64 `json:"mean" yaml:"mean"`
+	Std  float64 `json:"std" yaml:"std"`
+	Min  float64 `json:"min" yaml:"min"`
+	Max  float64 `json:"max" yaml:"max"`
+}
+
+func (v *Value) set(f valueFields) {
+	*v = Value{Dist: f.Dist, Mean: f.Mean, Std: f.Std, Min: f.Min, Max: f.Max}
+}
+
+// UnmarshalJSON accepts a number or a distribution object
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var x float64
+	if err := json.Unmarshal(data, &x); err == nil {
+		*v = Value{Fixed: x}
+		return nil
+	}
+	var f valueFields
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	v.set(f)
+	return nil
+}
+
+// UnmarshalYAML accepts a number or a distribution mapping
+func (v *Value) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var x float64
+		if err := node.Decode(&x); err != nil {
+			return err
+		}
+		*v = Value{Fixed: x}
+		return nil
+	}
+	var f valueFields
+	if err := node.Decode(&f); err != nil {
+		return err
+	}
+	v.set(f)
+	return nil
+}
+
+// MarshalJSON writes a fixed value as a number
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.Dist == "" {
+		return json.Marshal(v.Fixed)
+	}
+	return json.Marshal(valueFields{Dist: v.Dist, Mean: v.Mean, Std: v.Std, Min: v.Min, Max: v.Max})
+}
+
+// draw returns the fixed value or a draw of the distribution
+func (v Value) draw(rng *rand.Rand) float64 {
+	switch v.Dist {
+	case "normal":
+		return rng.NormFloat64()*v.Std + v.Mean
+	case "halfnormal":
+		return math.Abs(rng.NormFloat64()) * v.Std
+	case "uniform":
+		return v.Min + rng.Float64()*(v.Max-v.Min)
+	default:
+		return v.Fixed
+	}
+}
+
+// drawLength returns a positive integer length
+func (v Value) drawLength(rng *rand.Rand) int {
+	if v.Dist == "uniform" {
+		return rng.Intn(int(v.Max-v.Min)) + int(v.Min)
+	}
+	return max(1, int(math.Round(v.draw(rng))))
+}
+
+func (v Value) validate() error {
+	switch v.Dist {
+	case "", "normal", "halfnormal":
+		return nil
+	case "uniform":
+		if v.Max <= v.Min {
+			return fmt.Errorf("uniform needs min < max")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown dist %q", v.Dist)
+	}
+}
+
+// validateLength is validate for the lengths, see drawLength
+func (v Value) validateLength() error {
+	if err := v.validate(); err != nil {
+		return err
+	}
+	if v.Dist == "uniform" && (v.Min < 1 || v.Min != math.Trunc(v.Min) || v.Max != math.Trunc(v.Max)) {
+		return fmt.Errorf("uniform length needs integer bounds with 1 <= min < max")
+	}
+	return nil
+}
+
+// scenarioParams are the parameters of each distribution, in the order they are drawn
+var scenarioParams = map[string][]string{
+	"normal":    {"mean", "std"},
+	"poisson":   {"rate"},
+	"bernoulli": {"p"},
+	"ar":        {"phi", "std"},
+	"trend":     {"slope", "std"},
+}
+
+// ParseScenario decodes a scenario, format is "json" or "yaml"
+func ParseScenario(data []byte, format string) (ScenarioSpec, error) {
+	var spec ScenarioSpec
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &spec)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &spec)
+	default:
+		return spec, fmt.Errorf("unknown scenario format %q", format)
+	}
+	if err != nil {
+		return spec, err
+	}
+	return spec, spec.Validate()
+}
+
+// LoadScenario reads a scenario file, the format comes from the extension
+func LoadScenario(path string) (ScenarioSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ScenarioSpec{}, err
+	}
+	spec, err := ParseScenario(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return spec, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Validate checks the distributions and their parameters
+func (spec ScenarioSpec) Validate() error {
+	if len(spec.Segments) == 0 {
+		return fmt.Errorf("no segments")
+	}
+	for i, seg := range spec.Segments {
+		names, ok := scenarioParams[seg.Distribution]
+		if !ok {
+			return fmt.Errorf("segment %d: unknown distribution %q", i, seg.Distribution)
+		}
+		if len(seg.Params) != len(names) {
+			return fmt.Errorf("segment %d: %s needs the params %v", i, seg.Distribution, names)
+		}
+		for _, name := range names {
+			v, ok := seg.Params[name]
+			if !ok {
+				return fmt.Errorf("segment %d: %s needs the params %v", i, seg.Distribution, names)
+			}
+			if err := v.validate(); err != nil {
+				return fmt.Errorf("segment %d: param %s: %w", i, name, err)
+			}
+		}
+		if err := seg.Length.validateLength(); err != nil {
+			return fmt.Errorf("segment %d: length: %w", i, err)
+		}
+		if seg.Repeat < 0 {
+			return fmt.Errorf("segment %d: negative repeat", i)
+		}
+	}
+	if spec.Seasonality != nil && spec.Seasonality.Period <= 0 {
+		return fmt.Errorf("seasonality needs a positive period")
+	}
+	return nil
+}
+
+// Simulate builds the series of the scenario with its ground truth
+func (spec ScenarioSpec) Simulate() (Simulation, error) {
+	if err := spec.Validate(); err != nil {
+		return Simulation{}, err
+	}
+	rng := rand.New(rand.NewSource(spec.Seed))
+	// @ 1. expand the repeats and draw all the lengths first
+	var segments []SegmentSpec
+	for _, seg := range spec.Segments {
+		for i := 0; i < max(1, seg.Repeat); i++ {
+			segments = append(segments, seg)
+		}
+	}
+	lengths := make([]int, len(segments))
+	for i, seg := range segments {
+		lengths[i] = seg.Length.drawLength(rng)
+	}
+	// @ 2. the parameters, the data and the anomalies of each segment
+	var sim Simulation
+	level, x := 0.0, 0.0
+	for i, seg := range segments {
+		l := lengths[i]
+		params := make([]float64, 0, 2)
+		for _, name := range scenarioParams[seg.Distribution] {
+			params = append(params, seg.Params[name].draw(rng))
+		}
+		var value float64
+		var draw func(j int) float64
+		switch seg.Distribution {
+		case "normal":
+			value = params[0]
+			draw = func(int) float64 { return rng.NormFloat64()*params[1] + params[0] }
+		case "poisson":
+			value = params[0]
+			draw = func(int) float64 { return float64(poisson(rng, params[0])) }
+		case "bernoulli":
+			value = params[0]
+			draw = func(int) float64 {
+				if rng.Float64() < params[0] {
+					return 1
+				}
+				return 0
+			}
+		case "ar":
+			draw = func(int) float64 {
+				x = params[0]*x + rng.NormFloat64()*params[1]
+				return x
+			}
+		case "trend":
+			start := level
+			value = start + params[0]*float64(l-1)/2
+			params = append([]float64{start}, params...)
+			draw = func(j int) float64 { return start + params[1]*float64(j) + rng.NormFloat64()*params[2] }
+			level = start + params[1]*float64(l)
+		}
+		noise := seg.Noise
+		from := len(sim.Data)
+		sim.addSegment(l, value, params, func(j int) float64 {
+			v := draw(j)
+			if noise > 0 {
+				v += rng.NormFloat64() * noise
+			}
+			return v
+		})
+		if seg.Anomalies != nil {
+			if o := seg.Anomalies.Outliers; o != nil {
+				sim.addOutliers(rng, from, from+l, o.Rate, o.Scale)
+			}
+			if seg.Anomalies.Missing > 0 {
+				sim.addMissing(rng, from, from+l, seg.Anomalies.Missing)
+			}
+		}
+	}
+	// @ 3. the seasonal component over the whole series
+	if s := spec.Seasonality; s != nil {
+		sim.AddSeasonality(s.Period, s.Amplitude)
+	}
+	return sim, nil
+}
@@ -0,0 +1,89 @@
+package cpd
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the checked in spec regenerates the series of main.go exactly
+func TestScenarioDataInput(t *testing.T) {
+	spec, err := LoadScenario("../data/scenarios/data_input.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "data_input", spec.Name)
+	sim, err := spec.Simulate()
+	assert.Nil(t, err)
+	assert.Equal(t, SimulateNormal(5, 50, 1000, 100), sim)
+
+	// and the checked in data_input.csv, which has 6 decimals
+	checked := MustReadData("../data_input.csv")
+	assert.Equal(t, len(sim.Data), len(checked))
+	for i, x := range checked {
+		if !assert.InDelta(t, sim.Data[i], x, 5e-7, "value %d", i) {
+			break
+		}
+	}
+
+	// the same spec in JSON gives the same series
+	data, err := json.Marshal(spec)
+	assert.Nil(t, err)
+	fromJSON, err := ParseScenario(data, "json")
+	assert.Nil(t, err)
+	assert.Equal(t, spec, fromJSON)
+}
+
+// test the distributions, the anomalies and the seasonality of a spec
+func TestScenarioAnomalies(t *testing.T) {
+	spec, err := LoadScenario("../data/scenarios/anomalies.json")
+	assert.Nil(t, err)
+	sim, err := spec.Simulate()
+	assert.Nil(t, err)
+	again, _ := spec.Simulate()
+	assert.Equal(t, len(sim.Data), len(again.Data))
+	assert.Equal(t, sim.Changepoints, again.Changepoints)
+
+	assert.Equal(t, 5, len(sim.Segments))
+	assert.Equal(t, []int{200, 400, 600}, sim.Changepoints[:3])
+	assert.Equal(t, len(sim.Data), len(sim.Season))
+	// the trend starts from 0, the params are the start level, the slope and the std
+	assert.Equal(t, []float64{0, 0.05, 0.5}, sim.Segments[1].Params)
+	assert.InDelta(t, 4, sim.Segments[3].Value, 1e-9)
+	for _, i := range sim.Outliers {
+		assert.Less(t, i, 200)
+	}
+	assert.NotEmpty(t, sim.Outliers)
+	for _, i := range sim.Missing {
+		assert.GreaterOrEqual(t, i, 400)
+		assert.Less(t, i, 600)
+		assert.True(t, math.IsNaN(sim.Data[i]))
+	}
+	assert.NotEmpty(t, sim.Missing)
+}
+
+// test the invalid specs are rejected with the segment in the message
+func TestScenarioErrors(t *testing.T) {
+	_, err := ParseScenario([]byte("segments:\n  - distribution: gamma\n    length: 10\n"), "yaml")
+	assert.EqualError(t, err, `segment 0: unknown distribution "gamma"`)
+	_, err = ParseScenario([]byte(`{"segments": [{"distribution": "poisson", "length": 10, "params": {"mean": 1}}]}`), "json")
+	assert.EqualError(t, err, "segment 0: poisson needs the params [rate]")
+	_, err = ParseScenario([]byte(`{"segments": [{"distribution": "bernoulli", "length": {"dist": "cauchy"}, "params": {"p": 0.5}}]}`), "json")
+	assert.EqualError(t, err, `segment 0: length: unknown dist "cauchy"`)
+	for _, length := range []string{"{dist: uniform, min: 1, max: 1.5}", "{dist: uniform, min: -5, max: 5}", "{dist: uniform, min: 0.5, max: 10}"} {
+		_, err = ParseScenario([]byte("segments:\n  - distribution: bernoulli\n    length: "+length+"\n    params: {p: 0.5}\n"), "yaml")
+		assert.EqualError(t, err, "segment 0: length: uniform length needs integer bounds with 1 <= min < max", length)
+	}
+	_, err = ParseScenario([]byte("segments:\n  - distribution: bernoulli\n    length: {dist: uniform, min: 3, max: 3}\n    params: {p: 0.5}\n"), "yaml")
+	assert.EqualError(t, err, "segment 0: length: uniform needs min < max")
+	// max is exclusive
+	spec, err := ParseScenario([]byte("segments:\n  - distribution: bernoulli\n    repeat: 20\n    length: {dist: uniform, min: 1, max: 2}\n    params: {p: 0.5}\n"), "yaml")
+	assert.Nil(t, err)
+	sim, err := spec.Simulate()
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(sim.Data))
+	_, err = ParseScenario([]byte("{}"), "toml")
+	assert.Error(t, err)
+	_, err = LoadScenario("missing.yaml")
+	assert.Error(t, err)
+}
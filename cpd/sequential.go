@@ -0,0 +1,185 @@
+package cpd
+
+import "math"
+
+// * Side selects which shifts the control chart detectors look for.
+type Side int
+
+const (
+	// Upper looks for the increase of the mean
+	Upper Side = iota
+	// Lower looks for the decrease of the mean
+	Lower
+	// TwoSided looks for both
+	TwoSided
+)
+
+// * runningStats is the Welford online mean and variance of the current segment.
+type runningStats struct {
+	n        int
+	mean, m2 float64
+}
+
+func (s *runningStats) add(x float64) {
+	s.n++
+	d := x - s.mean
+	s.mean += d / float64(s.n)
+	s.m2 += d * (x - s.mean)
+}
+
+func (s *runningStats) variance() float64 {
+	if s.n < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.n-1)
+}
+
+// * CUSUM is the tabular CUSUM control chart with a self-starting reference:
+// * the mean and the variance of the current segment are estimated online,
+// * the first warmup values of every segment only feed the estimate.
+// * k is the allowance and h the decision threshold, both in sigma units.
+type CUSUM struct {
+	k, h   float64
+	side   Side
+	warmup int
+	ref    runningStats
+	// gPos and gNeg are the upper and lower statistics, the starts are
+	// the last steps where they were 0, the estimate of the changepoint
+	gPos, gNeg         float64
+	startPos, startNeg int
+	// restart is the first step of the reference, the alarm
+	step, restart int
+}
+
+// NewCUSUM returns a new CUSUM, a common choice is k = 0.5 and h = 5
+func NewCUSUM(k, h float64, side Side, warmup int) *CUSUM {
+	if warmup < 2 {
+		panic("CUSUM needs a warmup of at least 2 values to estimate the variance")
+	}
+	return &CUSUM{k: k, h: h, side: side, warmup: warmup}
+}
+
+// Update makes CUSUM a Detector
+func (c *CUSUM) Update(x float64) []Event {
+	t := c.step
+	c.step++
+	if c.ref.n < c.warmup {
+		c.ref.add(x)
+		c.startPos, c.startNeg = t+1, t+1
+		return nil
+	}
+	z := (x - c.ref.mean) / math.Sqrt(math.Max(c.ref.variance(), 1e-12))
+	if c.side != Lower {
+		c.gPos = math.Max(0, c.gPos+z-c.k)
+		if c.gPos == 0 {
+			c.startPos = t + 1
+		}
+	}
+	if c.side != Upper {
+		c.gNeg = math.Max(0, c.gNeg-z-c.k)
+		if c.gNeg == 0 {
+			c.startNeg = t + 1
+		}
+	}
+	stat, start := c.gPos, c.startPos
+	if c.gNeg > c.gPos {
+		stat, start = c.gNeg, c.startNeg
+	}
+	if stat <= c.h {
+		c.ref.add(x)
+		return nil
+	}
+	// @ alarm: report the estimated changepoint, restart the reference from the alarm,
+	// @ the values between the two are not used
+	e := Event{Kind: Changepoint, Index: start, Detected: t, Confirmed: t, Latency: t - start, Score: stat}
+	c.restart = t
+	c.ref = runningStats{}
+	c.gPos, c.gNeg = 0, 0
+	c.ref.add(x)
+	c.startPos, c.startNeg = t+1, t+1
+	return []Event{e}
+}
+
+// State returns the larger of the two statistics and the reference estimate,
+// the run length counts the values since the reference restarted
+func (c *CUSUM) State() State {
+	return State{
+		Step:      c.step,
+		RunLength: c.step - c.restart,
+		Statistic: math.Max(c.gPos, c.gNeg),
+		Mean:      c.ref.mean,
+		Variance:  c.ref.variance(),
+	}
+}
+
+// * PageHinkley is the Page-Hinkley test: the cumulative deviation from the
+// * running mean of the current segment, m_T = sum(x_t - mean_t - delta),
+// * alarms when m_T - min(m_t) exceeds lambda. delta and lambda are in data units.
+type PageHinkley struct {
+	delta, lambda float64
+	side          Side
+	minSamples    int
+	ref           runningStats
+	// mPos and mNeg are the cumulative sums for the increase and the decrease,
+	// with their extremes and where the extremes were
+	mPos, mNeg     float64
+	minPos, maxNeg float64
+	argPos, argNeg int
+	// restart is the first step of the running mean, the one after the alarm
+	step, restart int
+}
+
+// NewPageHinkley returns a new PageHinkley, no alarm is raised before
+// minSamples values of the current segment are seen
+func NewPageHinkley(delta, lambda float64, side Side, minSamples int) *PageHinkley {
+	return &PageHinkley{delta: delta, lambda: lambda, side: side, minSamples: minSamples}
+}
+
+// Update makes PageHinkley a Detector
+func (ph *PageHinkley) Update(x float64) []Event {
+	t := ph.step
+	ph.step++
+	ph.ref.add(x)
+	ph.mPos += x - ph.ref.mean - ph.delta
+	ph.mNeg += x - ph.ref.mean + ph.delta
+	if ph.mPos < ph.minPos || ph.ref.n == 1 {
+		ph.minPos, ph.argPos = ph.mPos, t+1
+	}
+	if ph.mNeg > ph.maxNeg || ph.ref.n == 1 {
+		ph.maxNeg, ph.argNeg = ph.mNeg, t+1
+	}
+	stat, start := ph.statistic()
+	if ph.ref.n < ph.minSamples || stat <= ph.lambda {
+		return nil
+	}
+	// @ alarm: restart the test from the current value
+	e := Event{Kind: Changepoint, Index: start, Detected: t, Confirmed: t, Latency: t - start, Score: stat}
+	ph.restart = t + 1
+	ph.ref = runningStats{}
+	ph.mPos, ph.mNeg, ph.minPos, ph.maxNeg = 0, 0, 0, 0
+	return []Event{e}
+}
+
+// statistic returns the larger of the enabled statistics and its changepoint estimate
+func (ph *PageHinkley) statistic() (float64, int) {
+	up, down := ph.mPos-ph.minPos, ph.maxNeg-ph.mNeg
+	switch {
+	case ph.side == Upper || (ph.side == TwoSided && up >= down):
+		return up, ph.argPos
+	default:
+		return down, ph.argNeg
+	}
+}
+
+// State returns the Page-Hinkley statistic and the running mean of the segment,
+// the run length counts the values of the running mean
+func (ph *PageHinkley) State() State {
+	stat, _ := ph.statistic()
+	return State{
+		Step:      ph.step,
+		RunLength: ph.step - ph.restart,
+		Statistic: stat,
+		Mean:      ph.ref.mean,
+		Variance:  ph.ref.variance(),
+	}
+}
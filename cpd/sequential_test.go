@@ -0,0 +1,79 @@
+package cpd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the two-sided control charts find the partition of ../data/data_output.csv
+func TestCUSUMAndPageHinkley(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")
+	truth := []int{58, 132, 249, 402, 539, 668}
+
+	for _, d := range []Detector{
+		NewCUSUM(0.5, 8, TwoSided, 20),
+		NewPageHinkley(0.5, 50, TwoSided, 20),
+	} {
+		events := RunDetector(d, data)
+		assert.Equal(t, len(truth), len(events))
+		for i, e := range events {
+			assert.Equal(t, Changepoint, e.Kind)
+			assert.InDelta(t, truth[i], e.Index, 2)
+			assert.GreaterOrEqual(t, e.Detected, e.Index)
+			// the alarm comes at most a few dozen steps late
+			assert.Less(t, e.Latency, 40)
+		}
+		// the reference is the last segment, mean 15.3
+		state := d.State()
+		assert.Equal(t, len(data), state.Step)
+		assert.InDelta(t, 15.3, state.Mean, 0.1)
+	}
+}
+
+// test the one-sided CUSUM only looks for the increase of the mean
+func TestCUSUMOneSided(t *testing.T) {
+	data := make([]float64, 0)
+	for i := 0; i < 300; i++ {
+		level := 0.0
+		switch {
+		case i >= 100 && i < 200:
+			level = -5
+		case i >= 200:
+			level = 5
+		}
+		data = append(data, level+0.1*float64((i*7)%5-2))
+	}
+	upper := RunDetector(NewCUSUM(0.5, 5, Upper, 20), data)
+	lower := RunDetector(NewCUSUM(0.5, 5, Lower, 20), data)
+	assert.Equal(t, 1, len(upper))
+	assert.InDelta(t, 200, upper[0].Index, 1)
+	assert.Equal(t, 1, len(lower))
+	assert.InDelta(t, 100, lower[0].Index, 1)
+}
+
+// test the run length right after an alarm counts the values of the new reference
+func TestSequentialRunLengthAfterAlarm(t *testing.T) {
+	// a small shift, the alarms come some steps after the change
+	data := SimulateMeanShift([]int{100, 100}, []float64{0, 1.5}, 1, 1).Data
+	cusum := NewCUSUM(0.5, 5, TwoSided, 20)
+	ph := NewPageHinkley(0.5, 10, TwoSided, 20)
+	for _, d := range []Detector{cusum, ph} {
+		for i, x := range data {
+			if events := d.Update(x); len(events) > 0 {
+				assert.Equal(t, i, events[0].Detected)
+				assert.Greater(t, events[0].Latency, 0)
+				break
+			}
+		}
+	}
+	assert.Equal(t, cusum.ref.n, cusum.State().RunLength)
+	assert.Equal(t, 1, cusum.State().RunLength)
+	assert.Equal(t, ph.ref.n, ph.State().RunLength)
+	assert.Equal(t, 0, ph.State().RunLength)
+	// and it grows with the reference
+	cusum.Update(data[0])
+	ph.Update(data[0])
+	assert.Equal(t, cusum.ref.n, cusum.State().RunLength)
+	assert.Equal(t, ph.ref.n, ph.State().RunLength)
+}
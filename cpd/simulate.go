@@ -3,28 +3,336 @@ package cpd
 import (
 	"math"
 	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
 )
 
+// * Simulation is a synthetic series with its ground truth.
+// * Every draw comes from the seeded source, so the same seed gives the same series.
+type Simulation struct {
+	Data []float64
+	// Series is the multivariate data, one row per step, Data is nil then
+	Series [][]float64
+	// Changepoints are the first indices of the segments, but the first one
+	Changepoints []int
+	// Segments are the true segments, Value is the mean and Params the parameters
+	Segments []Segment
+	// Outliers and Missing are the indices of the injected outliers and NaNs
+	Outliers, Missing []int
+	// Season is the seasonal component added to Data, if any
+	Season []float64
+}
+
+// Lengths returns the length of each segment
+func (s Simulation) Lengths() []int {
+	res := make([]int, len(s.Segments))
+	for i, seg := range s.Segments {
+		res[i] = seg.End - seg.Start
+	}
+	return res
+}
+
+// SimulateNormal generates num segments with lengths in [minl, maxl) of
+// normally distributed data, with mean ~ N(0, 10^2) and std ~ |N(0, 1)|.
+// The Params of a segment are the mean and the std.
+func SimulateNormal(num, minl, maxl int, seed int64) Simulation {
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	lengths := make([]int, num)
+	for i := range lengths {
+		lengths[i] = rng.Intn(maxl-minl) + minl
+	}
+	for _, l := range lengths {
+		mean := rng.NormFloat64() * 10
+		std := math.Abs(rng.NormFloat64())
+		sim.addSegment(l, mean, []float64{mean, std}, func(int) float64 {
+			return rng.NormFloat64()*std + mean
+		})
+	}
+	return sim
+}
+
 // GenerateNormalTimeSeries generates a time series of normally distributed data.
+// It returns the segment lengths and the data of SimulateNormal.
 func GenerateNormalTimeSeries(num, minl, maxl int, seed int64) ([]int, []float64) {
-	source := rand.NewSource(seed)
-	rng := rand.New(source)
+	sim := SimulateNormal(num, minl, maxl, seed)
+	return sim.Lengths(), sim.Data
+}
+
+// SimulateMeanShift generates normal segments with the given means and a common std
+func SimulateMeanShift(lengths []int, means []float64, std float64, seed int64) Simulation {
+	checkSegments(lengths, means)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for i, l := range lengths {
+		mean := means[i]
+		sim.addSegment(l, mean, []float64{mean, std}, func(int) float64 {
+			return rng.NormFloat64()*std + mean
+		})
+	}
+	return sim
+}
 
-	var data []float64
-	partition := make([]int, num)
+// SimulateVariance generates normal segments with a common mean and the given stds,
+// the Params of a segment are the mean and the std
+func SimulateVariance(lengths []int, stds []float64, mean float64, seed int64) Simulation {
+	checkSegments(lengths, stds)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for i, l := range lengths {
+		std := stds[i]
+		sim.addSegment(l, mean, []float64{mean, std}, func(int) float64 {
+			return rng.NormFloat64()*std + mean
+		})
+	}
+	return sim
+}
 
-	for i := 0; i < num; i++ {
-		partition[i] = rng.Intn(maxl-minl) + minl
+// SimulateTrend generates a continuous piecewise linear trend from 0 with the
+// given slopes plus normal noise. The Params of a segment are the level at its
+// start and the slope, Value is the level in the middle of the segment.
+func SimulateTrend(lengths []int, slopes []float64, std float64, seed int64) Simulation {
+	checkSegments(lengths, slopes)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	level := 0.0
+	for i, l := range lengths {
+		start, slope := level, slopes[i]
+		sim.addSegment(l, start+slope*float64(l-1)/2, []float64{start, slope}, func(j int) float64 {
+			return start + slope*float64(j) + rng.NormFloat64()*std
+		})
+		level = start + slope*float64(l)
 	}
+	return sim
+}
 
-	for _, p := range partition {
-		mean := rand.NormFloat64() * 10
-		variance := math.Abs(rand.NormFloat64())
-		for j := 0; j < p; j++ {
-			tdata := rand.NormFloat64()*variance + mean
-			data = append(data, tdata)
+// SimulateAR generates an AR(1) process x = phi * x[t-1] + e, e ~ N(0, std^2),
+// with the coefficient phi of each segment, the state carries over the changes.
+// The Params of a segment are phi and std.
+func SimulateAR(lengths []int, phis []float64, std float64, seed int64) Simulation {
+	checkSegments(lengths, phis)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	x := 0.0
+	for i, l := range lengths {
+		phi := phis[i]
+		sim.addSegment(l, 0, []float64{phi, std}, func(int) float64 {
+			x = phi*x + rng.NormFloat64()*std
+			return x
+		})
+	}
+	return sim
+}
+
+// SimulatePoisson generates counts with the rate of each segment
+func SimulatePoisson(lengths []int, rates []float64, seed int64) Simulation {
+	checkSegments(lengths, rates)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for i, l := range lengths {
+		rate := rates[i]
+		sim.addSegment(l, rate, []float64{rate}, func(int) float64 {
+			return float64(poisson(rng, rate))
+		})
+	}
+	return sim
+}
+
+// SimulateBernoulli generates 0/1 data with the success probability of each segment
+func SimulateBernoulli(lengths []int, probs []float64, seed int64) Simulation {
+	checkSegments(lengths, probs)
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for i, l := range lengths {
+		p := probs[i]
+		sim.addSegment(l, p, []float64{p}, func(int) float64 {
+			if rng.Float64() < p {
+				return 1
+			}
+			return 0
+		})
+	}
+	return sim
+}
+
+// SimulateCovariance generates zero mean multivariate normal data in Series
+// with the covariance matrix of each segment. The Params of a segment are the
+// covariance matrix, row by row.
+func SimulateCovariance(lengths []int, covs []*mat.SymDense, seed int64) Simulation {
+	if len(lengths) != len(covs) {
+		panic("Each segment needs its parameter")
+	}
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for i, l := range lengths {
+		d := covs[i].SymmetricDim()
+		var chol mat.Cholesky
+		if !chol.Factorize(covs[i]) {
+			panic("The covariance matrix must be positive definite")
+		}
+		var L mat.TriDense
+		chol.LTo(&L)
+		params := make([]float64, 0, d*d)
+		for r := 0; r < d; r++ {
+			for c := 0; c < d; c++ {
+				params = append(params, covs[i].At(r, c))
+			}
+		}
+		start := len(sim.Series)
+		if start > 0 {
+			sim.Changepoints = append(sim.Changepoints, start)
 		}
+		// @ x = L z, z ~ N(0, I)
+		for j := 0; j < l; j++ {
+			z := mat.NewVecDense(d, nil)
+			for k := 0; k < d; k++ {
+				z.SetVec(k, rng.NormFloat64())
+			}
+			x := mat.NewVecDense(d, nil)
+			x.MulVec(&L, z)
+			sim.Series = append(sim.Series, TransformVecDenseToSlice(x))
+		}
+		sim.Segments = append(sim.Segments, Segment{
+			Start: start, End: start + l,
+			From: float64(start), To: float64(start + l),
+			Params: params,
+		})
 	}
+	return sim
+}
+
+// AddOutliers adds to each value with probability rate a spike of +-scale,
+// the value stays under it, the indices go to Outliers
+func (s *Simulation) AddOutliers(rate, scale float64, seed int64) *Simulation {
+	s.addOutliers(rand.New(rand.NewSource(seed)), 0, len(s.Data), rate, scale)
+	return s
+}
 
-	return partition, data
+// addOutliers injects the outliers into Data[from:to] with the draws of rng
+func (s *Simulation) addOutliers(rng *rand.Rand, from, to int, rate, scale float64) {
+	for i := from; i < to; i++ {
+		if rng.Float64() < rate {
+			sign := 1.0
+			if rng.Float64() < 0.5 {
+				sign = -1
+			}
+			s.Data[i] += sign * scale
+			s.Outliers = append(s.Outliers, i)
+		}
+	}
+}
+
+// AddSeasonality adds amplitude * sin(2 pi t / period) to the data,
+// the component goes to Season
+func (s *Simulation) AddSeasonality(period int, amplitude float64) *Simulation {
+	s.Season = make([]float64, len(s.Data))
+	for i := range s.Data {
+		s.Season[i] = amplitude * math.Sin(2*math.Pi*float64(i)/float64(period))
+		s.Data[i] += s.Season[i]
+	}
+	return s
+}
+
+// AddMissing replaces each value with probability rate by NaN,
+// the indices go to Missing
+func (s *Simulation) AddMissing(rate float64, seed int64) *Simulation {
+	s.addMissing(rand.New(rand.NewSource(seed)), 0, len(s.Data), rate)
+	return s
+}
+
+// addMissing injects the NaNs into Data[from:to] with the draws of rng
+func (s *Simulation) addMissing(rng *rand.Rand, from, to int, rate float64) {
+	for i := from; i < to; i++ {
+		if rng.Float64() < rate {
+			s.Data[i] = math.NaN()
+			s.Missing = append(s.Missing, i)
+		}
+	}
+}
+
+// SimulatePrior draws n values from the generative model OCPD assumes:
+// a segment with L values ends with probability hazardFunction(lam, L-1), as
+// the run lengths of OCPD, and each segment draws a prior k of the mixture by
+// its weight, then the precision tau ~ Gamma(alpha, beta) and the mean
+// mu ~ N(mu0, 1/(kappa tau)) from the Normal-Gamma prior k, then x ~ N(mu, 1/tau).
+// Only the prior of the model is used, it is not updated.
+// The Params of a segment are mu, the std 1/sqrt(tau) and k.
+func SimulatePrior(n int, lam float64, hazardFunction func(float64, []float64) []float64, prior *StudentT_Bayesian_Update, seed int64) Simulation {
+	rng := rand.New(rand.NewSource(seed))
+	var sim Simulation
+	for len(sim.Data) < n {
+		// @ 1. the parameters of the new segment
+		k := 0
+		u, acc := rng.Float64(), prior.weight0[0]
+		for u >= acc && k < len(prior.weight0)-1 {
+			k++
+			acc += prior.weight0[k]
+		}
+		tau := gamma(rng, prior.alpha0[k]) / prior.beta0[k]
+		mu := prior.mu0[k] + rng.NormFloat64()/math.Sqrt(prior.kappa0[k]*tau)
+		std := 1 / math.Sqrt(tau)
+		// @ 2. the length from the hazard, the last segment is cut at n
+		l := 1
+		for len(sim.Data)+l < n && rng.Float64() >= hazardFunction(lam, []float64{float64(l - 1)})[0] {
+			l++
+		}
+		sim.addSegment(l, mu, []float64{mu, std, float64(k)}, func(int) float64 {
+			return mu + rng.NormFloat64()*std
+		})
+	}
+	return sim
+}
+
+// checkSegments panics if the segments and their parameters do not match
+func checkSegments(lengths []int, params []float64) {
+	if len(lengths) != len(params) {
+		panic("Each segment needs its parameter")
+	}
+}
+
+// poisson draws a Poisson count by the unit rate arrivals before rate
+func poisson(rng *rand.Rand, rate float64) int {
+	n := 0
+	for t := rng.ExpFloat64(); t < rate; t += rng.ExpFloat64() {
+		n++
+	}
+	return n
+}
+
+// gamma draws from Gamma(shape, 1), Marsaglia & Tsang (2000),
+// the shape below 1 is boosted by U^(1/shape)
+func gamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return gamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// addSegment appends l values of draw, j is the position in the segment
+func (s *Simulation) addSegment(l int, value float64, params []float64, draw func(j int) float64) {
+	start := len(s.Data)
+	if start > 0 {
+		s.Changepoints = append(s.Changepoints, start)
+	}
+	for j := 0; j < l; j++ {
+		s.Data = append(s.Data, draw(j))
+	}
+	s.Segments = append(s.Segments, Segment{
+		Start: start, End: start + l,
+		From: float64(start), To: float64(start + l),
+		Value: value, Params: params,
+	})
 }
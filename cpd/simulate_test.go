@@ -1,9 +1,11 @@
 package cpd
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
 )
 
 func TestGenerateNormalTimeSeries(t *testing.T) {
@@ -19,4 +21,162 @@ func TestGenerateNormalTimeSeries(t *testing.T) {
 		tmpsum += v
 	}
 	assert.Equal(t, tmpsum, len(data))
-}
\ No newline at end of file
+}
+
+// test the simulation is reproducible and returns its ground truth
+func TestSimulateNormal(t *testing.T) {
+	sim := SimulateNormal(5, 50, 1000, 100)
+	assert.Equal(t, sim, SimulateNormal(5, 50, 1000, 100))
+	assert.NotEqual(t, sim.Data, SimulateNormal(5, 50, 1000, 101).Data)
+
+	assert.Equal(t, 4, len(sim.Changepoints))
+	assert.Equal(t, 5, len(sim.Segments))
+	for i, seg := range sim.Segments {
+		if i > 0 {
+			assert.Equal(t, sim.Changepoints[i-1], seg.Start)
+		}
+		// the sample mean is close to the true mean
+		data := sim.Data[seg.Start:seg.End]
+		n := float64(len(data))
+		assert.InDelta(t, seg.Params[0], SumSlice(data)/n, 5*seg.Params[1]/math.Sqrt(n))
+	}
+	assert.Equal(t, len(sim.Data), sim.Segments[4].End)
+
+	partition, data := GenerateNormalTimeSeries(5, 50, 1000, 100)
+	assert.Equal(t, sim.Lengths(), partition)
+	assert.Equal(t, sim.Data, data)
+}
+
+// test each scenario against its ground truth
+func TestScenarios(t *testing.T) {
+	lengths := []int{300, 300, 300}
+	mean := func(x []float64) float64 { return SumSlice(x) / float64(len(x)) }
+	std := func(x []float64) float64 {
+		m := mean(x)
+		return math.Sqrt(SumSlice(PowConstantSlice(AddConstantSlice(x, -m), 2)) / float64(len(x)))
+	}
+
+	shift := SimulateMeanShift(lengths, []float64{0, 3, -1}, 1, 1)
+	assert.Equal(t, []int{300, 600}, shift.Changepoints)
+	assert.Equal(t, shift.Changepoints, PELT(shift.Data, NewMeanCost(), Penalty{Kind: MBIC}, 2).Changepoints())
+
+	variance := SimulateVariance(lengths, []float64{1, 4, 0.5}, 2, 1)
+	for _, seg := range variance.Segments {
+		assert.InDelta(t, seg.Params[1], std(variance.Data[seg.Start:seg.End]), 0.15*seg.Params[1])
+	}
+	cps := PELT(variance.Data, NewMeanVarCost(), Penalty{Kind: BIC}, 5).Changepoints()
+	assert.Equal(t, 2, len(cps))
+
+	trend := SimulateTrend(lengths, []float64{0.1, -0.05, 0}, 0.5, 1)
+	// continuous at the changes
+	assert.InDelta(t, 30, trend.Segments[1].Params[0], 1e-9)
+	assert.InDelta(t, 15, trend.Segments[2].Params[0], 1e-9)
+	assert.InDelta(t, trend.Segments[1].Value, mean(trend.Data[300:600]), 0.2)
+
+	ar := SimulateAR(lengths, []float64{0.9, -0.5, 0}, 1, 1)
+	for _, seg := range ar.Segments {
+		x := ar.Data[seg.Start:seg.End]
+		// the lag one autocorrelation
+		num, den := 0.0, 0.0
+		for i := 1; i < len(x); i++ {
+			num += x[i] * x[i-1]
+			den += x[i-1] * x[i-1]
+		}
+		assert.InDelta(t, seg.Params[0], num/den, 0.15)
+	}
+
+	counts := SimulatePoisson(lengths, []float64{2, 8, 40}, 1)
+	for _, seg := range counts.Segments {
+		x := counts.Data[seg.Start:seg.End]
+		assert.InDelta(t, seg.Value, mean(x), 4*math.Sqrt(seg.Value/300))
+		assert.InDelta(t, math.Sqrt(seg.Value), std(x), 0.15*math.Sqrt(seg.Value))
+	}
+
+	bernoulli := SimulateBernoulli(lengths, []float64{0.1, 0.6, 0.3}, 1)
+	for _, seg := range bernoulli.Segments {
+		assert.InDelta(t, seg.Value, mean(bernoulli.Data[seg.Start:seg.End]), 0.1)
+	}
+
+	covs := []*mat.SymDense{
+		mat.NewSymDense(2, []float64{1, 0, 0, 1}),
+		mat.NewSymDense(2, []float64{1, 0.9, 0.9, 1}),
+	}
+	multi := SimulateCovariance([]int{2000, 2000}, covs, 1)
+	assert.Nil(t, multi.Data)
+	assert.Equal(t, 4000, len(multi.Series))
+	assert.Equal(t, []int{2000}, multi.Changepoints)
+	for _, seg := range multi.Segments {
+		cov := 0.0
+		for _, row := range multi.Series[seg.Start:seg.End] {
+			cov += row[0] * row[1] / 2000
+		}
+		assert.InDelta(t, seg.Params[1], cov, 0.1)
+	}
+}
+
+// test the injected outliers, seasonality and missing values are recorded
+func TestScenarioInjections(t *testing.T) {
+	clean := SimulateMeanShift([]int{500, 500}, []float64{0, 5}, 1, 2)
+	sim := SimulateMeanShift([]int{500, 500}, []float64{0, 5}, 1, 2)
+	sim.AddOutliers(0.01, 20, 3).AddSeasonality(50, 2).AddMissing(0.02, 4)
+
+	assert.NotEmpty(t, sim.Outliers)
+	assert.NotEmpty(t, sim.Missing)
+	outlier := map[int]bool{}
+	for _, i := range sim.Outliers {
+		outlier[i] = true
+	}
+	missing := map[int]bool{}
+	for _, i := range sim.Missing {
+		missing[i] = true
+		assert.True(t, math.IsNaN(sim.Data[i]))
+	}
+	for i, x := range sim.Data {
+		if missing[i] {
+			continue
+		}
+		diff := x - sim.Season[i] - clean.Data[i]
+		if outlier[i] {
+			assert.InDelta(t, 20, math.Abs(diff), 1e-9)
+		} else {
+			assert.InDelta(t, 0, diff, 1e-9)
+		}
+	}
+	assert.InDelta(t, 2, sim.Season[12]+sim.Season[13], 2)
+	assert.InDelta(t, 0, SumSlice(sim.Season[:50]), 1e-9)
+}
+
+// test the prior predictive series and the calibration of OfflineBOCPD on them
+func TestSimulatePrior(t *testing.T) {
+	newPrior := func() *StudentT_Bayesian_Update {
+		return NewStudentT_BU([]float64{5}, []float64{5}, []float64{0.05}, []float64{0})
+	}
+	sim := SimulatePrior(20000, 100, ConstantHazardSlice, newPrior(), 1)
+	assert.Equal(t, 20000, len(sim.Data))
+	assert.Equal(t, sim, SimulatePrior(20000, 100, ConstantHazardSlice, newPrior(), 1))
+	// geometric lengths with mean lam, the last one is cut
+	lengths := sim.Lengths()
+	mean := 0.0
+	for _, l := range lengths[:len(lengths)-1] {
+		mean += float64(l) / float64(len(lengths)-1)
+	}
+	assert.InDelta(t, 100, mean, 15)
+	// E[tau] = alpha / beta = 1, Var[mu] = beta / (kappa (alpha-1)) = 25
+	tau, mu2 := 0.0, 0.0
+	for _, seg := range sim.Segments {
+		tau += 1 / (seg.Params[1] * seg.Params[1]) / float64(len(sim.Segments))
+		mu2 += seg.Params[0] * seg.Params[0] / float64(len(sim.Segments))
+	}
+	assert.InDelta(t, 1, tau, 0.15)
+	assert.InDelta(t, 25, mu2, 8)
+
+	// under the true model the expected number of changepoints is the number of changepoints
+	predicted, actual := 0.0, 0.0
+	for seed := int64(0); seed < 20; seed++ {
+		sim := SimulatePrior(300, 100, ConstantHazardSlice, newPrior(), seed)
+		ob := NewOfflineBOCPD(sim.Data, 100, ConstantHazardSlice, newPrior())
+		predicted += SumSlice(ob.ChangepointProbabilities())
+		actual += float64(len(sim.Changepoints))
+	}
+	assert.InDelta(t, actual, predicted, 0.15*actual)
+}
@@ -0,0 +1,161 @@
+package cpd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoLayouts are the layouts ParseTime tries without a layout, before the numbers
+var autoLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTime reads a timestamp in the layout: "rfc3339", "unix" for the
+// seconds, "unixms" for the milliseconds, or a layout of the time package,
+// e.g. "02/01/2006 15:04". Without layout it tries RFC3339 and the ISO dates,
+// then a number of seconds, or of milliseconds from 1e11 on.
+// The timestamps without a zone are in UTC.
+func ParseTime(s, layout string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(layout) {
+	case "":
+		for _, l := range autoLayouts {
+			if t, err := time.Parse(l, s); err == nil {
+				return t, nil
+			}
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time format")
+		}
+		if math.Abs(v) >= 1e11 {
+			return parseUnix(s, time.Millisecond)
+		}
+		return parseUnix(s, time.Second)
+	case "rfc3339":
+		return time.Parse(time.RFC3339Nano, s)
+	case "unix":
+		return parseUnix(s, time.Second)
+	case "unixms":
+		return parseUnix(s, time.Millisecond)
+	default:
+		return time.Parse(layout, s)
+	}
+}
+
+// parseUnix reads a number of units from the epoch, exactly for the integers,
+// the number must fit an int64
+func parseUnix(s string, unit time.Duration) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if unit == time.Second {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		return time.UnixMilli(n).UTC(), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	// @ 2^63 is the first float out of the int64 range
+	if err != nil || math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return time.Time{}, fmt.Errorf("%q is not a unix time", s)
+	}
+	whole := math.Floor(v)
+	t, err := parseUnix(strconv.FormatFloat(whole, 'f', 0, 64), unit)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(time.Duration((v - whole) * float64(unit))), nil
+}
+
+// * TimedDetector is a Detector which takes the timestamps of the values,
+// * e.g. OCPD or ParticleOCPD with SetTimeScale.
+type TimedDetector interface {
+	Detector
+	// UpdateAt feeds one value with its timestamp, the events carry the times
+	UpdateAt(t time.Time, x float64) []Event
+}
+
+// StampEvents sets the wall clock times of the events from the timestamps of
+// the steps, the steps out of times keep the zero time
+func StampEvents(events []Event, times []time.Time) []Event {
+	at := func(i int) time.Time {
+		if i >= 0 && i < len(times) {
+			return times[i]
+		}
+		return time.Time{}
+	}
+	for i := range events {
+		events[i].Time = at(events[i].Index)
+		events[i].DetectedTime = at(events[i].Detected)
+		events[i].ConfirmedTime = at(events[i].Confirmed)
+	}
+	return events
+}
+
+// RunTimedDetector feeds all the timestamped data to the detector, through
+// UpdateAt for a TimedDetector, and collects the stamped events
+func RunTimedDetector(d Detector, times []time.Time, data []float64) []Event {
+	if len(times) != len(data) {
+		panic("times and data must have the same length")
+	}
+	events := make([]Event, 0)
+	td, timed := d.(TimedDetector)
+	for i, x := range data {
+		if timed {
+			events = append(events, td.UpdateAt(times[i], x)...)
+			continue
+		}
+		events = append(events, StampEvents(d.Update(x), times[:i+1])...)
+	}
+	return events
+}
+
+// * clock scales the hazard of the BOCPD detectors by the time elapsed since
+// * the previous sample, see OCPD.SetTimeScale. unit is the time unit of lam,
+// * dt the elapsed units of the step when scaled.
+type clock struct {
+	unit   time.Duration
+	scaled bool
+	dt     float64
+}
+
+func (c *clock) setUnit(unit time.Duration) {
+	if unit <= 0 {
+		panic("the time unit must be positive")
+	}
+	c.unit = unit
+}
+
+// tick appends t to the timestamps and scales the next step by the elapsed
+// time, when there is a unit; call done after the step
+func (c *clock) tick(times []time.Time, t time.Time) []time.Time {
+	if c.unit > 0 && len(times) > 0 {
+		elapsed := t.Sub(times[len(times)-1])
+		if elapsed < 0 {
+			panic("the timestamps must not go backwards")
+		}
+		c.scaled, c.dt = true, float64(elapsed)/float64(c.unit)
+	}
+	return append(times, t)
+}
+
+func (c *clock) done() {
+	c.scaled = false
+}
+
+// hazard returns H over the elapsed time of the step, H as it is out of UpdateAt
+func (c *clock) hazard(H []float64) []float64 {
+	if !c.scaled {
+		return H
+	}
+	return scaleHazard(H, c.dt)
+}
+
+// scaleHazard returns the probability of a change over dt units of time for
+// the hazards H of one unit: 1-(1-H)^dt
+func scaleHazard(H []float64, dt float64) []float64 {
+	res := make([]float64, len(H))
+	for i, h := range H {
+		res[i] = 1 - math.Pow(1-h, dt)
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+package cpd
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	at := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	for _, c := range []struct {
+		s, layout string
+		want      time.Time
+	}{
+		{"2023-11-14T22:13:20Z", "", at},
+		{"2023-11-15T00:13:20+02:00", "rfc3339", at},
+		{"2023-11-14 22:13:20", "", at},
+		{"2023-11-14", "", time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)},
+		{"1700000000", "", at},
+		{"1700000000", "unix", at},
+		{"1700000000.25", "", at.Add(250 * time.Millisecond)},
+		{"1700000000123", "", at.Add(123 * time.Millisecond)},
+		{"1700000000", "unixms", time.UnixMilli(1700000000).UTC()},
+		{" 14/11/2023 22:13 ", "02/01/2006 15:04", at.Add(-20 * time.Second)},
+	} {
+		got, err := ParseTime(c.s, c.layout)
+		assert.Nil(t, err, c.s)
+		assert.True(t, c.want.Equal(got), "%s: %v", c.s, got)
+	}
+	for _, c := range [][2]string{{"soon", ""}, {"1e400", "unix"}, {"1e20", "unix"}, {"-1e20", "unixms"}, {"1e20", ""}, {"99999999999999999999", "unix"}, {"2023-11-14", "unix"}, {"14/11/2023", "2006-01-02"}} {
+		_, err := ParseTime(c[0], c[1])
+		assert.Error(t, err, c[0])
+	}
+}
+
+// test the events of any detector get the times of their steps
+func TestRunTimedDetector(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := SimulateMeanShift([]int{100, 100}, []float64{0, 5}, 1, 1)
+	times := make([]time.Time, len(sim.Data))
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Minute)
+	}
+	events := RunTimedDetector(NewCUSUM(0.5, 8, TwoSided, 20), times, sim.Data)
+	assert.Equal(t, RunDetector(NewCUSUM(0.5, 8, TwoSided, 20), sim.Data)[0].Index, events[0].Index)
+	for _, e := range events {
+		assert.Equal(t, times[e.Index], e.Time)
+		assert.Equal(t, times[e.Confirmed], e.ConfirmedTime)
+	}
+
+	// OCPD is a TimedDetector, its result keeps the times
+	d := NewOCPD(250, ConstantHazardSlice, NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})).SetConfirmation(10, 0.5)
+	events = RunTimedDetector(d, times, sim.Data)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, start.Add(100*time.Minute), events[0].Time)
+	assert.Equal(t, events, d.Result().Events)
+	assert.Equal(t, times, d.Times)
+
+	assert.Equal(t, time.Time{}, StampEvents([]Event{{Index: 5}}, times[:3])[0].Time)
+	assert.Panics(t, func() { RunTimedDetector(d, times[:1], sim.Data) })
+}
+
+// test the hazard over the elapsed time
+func TestTimeScale(t *testing.T) {
+	newOCPD := func() *OCPD {
+		return NewOCPD(100, ConstantHazardSlice, NewStudentT_BU([]float64{1}, []float64{1}, []float64{1}, []float64{0}))
+	}
+	data := SimulateMeanShift([]int{50}, []float64{0}, 1, 1).Data
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// the regular samples of one unit are the steps
+	plain, timed := newOCPD(), newOCPD().SetTimeScale(time.Second)
+	for i, x := range data {
+		plain.OCPD_Update(x)
+		timed.UpdateAt(start.Add(time.Duration(i)*time.Second), x)
+	}
+	assert.InDeltaSlice(t, plain.Res, timed.Res, 1e-12)
+
+	// with a constant hazard Res[0] is the hazard of the step
+	gap := start.Add(time.Duration(len(data)+9) * time.Second)
+	timed.UpdateAt(gap, 0)
+	assert.InDelta(t, 1-math.Pow(0.99, 10), timed.Res[0], 1e-12)
+	timed.UpdateAt(gap, 0)
+	assert.Equal(t, 0.0, timed.Res[0])
+	timed.UpdateAt(gap.Add(500*time.Millisecond), 0)
+	assert.InDelta(t, 1-math.Sqrt(0.99), timed.Res[0], 1e-12)
+
+	// without a time scale the timestamps are only carried
+	carried := newOCPD()
+	carried.UpdateAt(start, 0)
+	carried.UpdateAt(start.Add(time.Hour), 0)
+	assert.InDelta(t, 0.01, carried.Res[0], 1e-12)
+
+	assert.Panics(t, func() { timed.UpdateAt(start, 0) })
+	assert.Panics(t, func() { newOCPD().SetTimeScale(0) })
+}
+
+// test the particle filter and the multiple-model detector scale their hazard too
+func TestTimeScaleOtherDetectors(t *testing.T) {
+	model := func() *StudentT_Bayesian_Update {
+		return NewStudentT_BU([]float64{1}, []float64{1}, []float64{1}, []float64{0})
+	}
+	data := SimulateMeanShift([]int{50}, []float64{0}, 1, 1).Data
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var pf TimedDetector = NewParticleOCPD(100, ConstantHazardSlice, model(), 100, 1).SetTimeScale(time.Second)
+	mm := NewMultiModelOCPD(100, ConstantHazardSlice, []ObservationModel{model(), model()}, []float64{1, 1}).SetTimeScale(time.Second)
+	for i, x := range data {
+		pf.UpdateAt(start.Add(time.Duration(i)*time.Second), x)
+		mm.UpdateAt(start.Add(time.Duration(i)*time.Second), x)
+	}
+	assert.InDelta(t, 0.01, pf.(*ParticleOCPD).Res[0], 1e-12)
+	assert.InDelta(t, 0.01, mm.Res[0], 1e-12)
+
+	gap := start.Add(time.Duration(len(data)+9) * time.Second)
+	pf.UpdateAt(gap, 0)
+	mm.UpdateAt(gap, 0)
+	assert.InDelta(t, 1-math.Pow(0.99, 10), pf.(*ParticleOCPD).Res[0], 1e-12)
+	assert.InDelta(t, 1-math.Pow(0.99, 10), mm.Res[0], 1e-12)
+	assert.Equal(t, len(data)+1, len(mm.Times))
+	assert.Panics(t, func() { mm.UpdateAt(start, 0) })
+}
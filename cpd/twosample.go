@@ -0,0 +1,295 @@
+package cpd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// * TwoSampleTest selects the statistic of the TwoSampleDetector.
+type TwoSampleTest int
+
+const (
+	// KS is the two-sample Kolmogorov-Smirnov statistic
+	KS TwoSampleTest = iota
+	// MMD is the unbiased squared maximum mean discrepancy with the RBF kernel
+	MMD
+)
+
+// * TwoSampleDetector is the model-free streaming detector: a reference window
+// * against the recent window, with the p-value of a two-sample test.
+// * The first refSize values of a segment fill the reference, then the recent
+// * window slides over the last winSize values and every step is tested.
+// * After an alarm the detector starts again with a new reference.
+// * As every step is a new test, alpha should be small.
+// * The event is at the best split of the windows, see split.
+// * For MMD the Score is the quadratic time statistic, while the asymptotic
+// * p-value is the one of the linear time statistic, see LinearMMDPValue:
+// * the null distribution of the quadratic one has no closed form.
+// * The permutation p-values use the Score itself.
+type TwoSampleDetector struct {
+	test             TwoSampleTest
+	refSize, winSize int
+	alpha            float64
+	// permutations is the number of permutations, 0 means the asymptotic p-value
+	permutations int
+	rng          *rand.Rand
+	ref, win     []float64
+	bandwidth    float64
+	step, lastCp int
+	stat, pvalue float64
+}
+
+// NewTwoSampleDetector returns a new TwoSampleDetector. With permutations > 0
+// the p-value cannot be below 1/(permutations+1), so alpha should not be either.
+func NewTwoSampleDetector(test TwoSampleTest, refSize, winSize int, alpha float64, permutations int, seed int64) *TwoSampleDetector {
+	if refSize < 2 || winSize < 2 || alpha <= 0 || alpha >= 1 {
+		panic("TwoSampleDetector needs windows of at least 2 values and 0 < alpha < 1")
+	}
+	return &TwoSampleDetector{
+		test:         test,
+		refSize:      refSize,
+		winSize:      winSize,
+		alpha:        alpha,
+		permutations: permutations,
+		rng:          rand.New(rand.NewSource(seed)),
+		ref:          make([]float64, 0, refSize),
+		win:          make([]float64, 0, winSize),
+		pvalue:       1,
+	}
+}
+
+// Update makes TwoSampleDetector a Detector, the event carries the p-value
+func (ts *TwoSampleDetector) Update(x float64) []Event {
+	t := ts.step
+	ts.step++
+	// @ 1. fill the reference window, then the recent one
+	if len(ts.ref) < ts.refSize {
+		ts.ref = append(ts.ref, x)
+		if len(ts.ref) == ts.refSize {
+			ts.bandwidth = MedianHeuristic(ts.ref)
+		}
+		return nil
+	}
+	ts.win = append(ts.win, x)
+	if len(ts.win) > ts.winSize {
+		ts.win = ts.win[1:]
+	}
+	if len(ts.win) < ts.winSize {
+		return nil
+	}
+	// @ 2. test the two windows
+	ts.stat = ts.statistic(ts.ref, ts.win)
+	if ts.permutations > 0 {
+		ts.pvalue = ts.permutationPValue()
+	} else if ts.test == KS {
+		ts.pvalue = KSPValue(ts.stat, len(ts.ref), len(ts.win))
+	} else {
+		ts.pvalue = LinearMMDPValue(ts.ref, ts.win, ts.bandwidth)
+	}
+	if ts.pvalue >= ts.alpha {
+		return nil
+	}
+	// @ 3. alarm: the change is in the recent window, start again after it
+	cp := t - ts.winSize + 1 + ts.split()
+	e := Event{Kind: Changepoint, Index: cp, Detected: t, Confirmed: t, Latency: t - cp, Score: ts.stat, PValue: ts.pvalue}
+	ts.lastCp = cp
+	ts.ref = ts.ref[:0]
+	ts.win = ts.win[:0]
+	return []Event{e}
+}
+
+// split returns the estimate of the change in the recent window: the split
+// of the reference and the recent window into before and after with the
+// largest statistic, scaled by n m / (n + m) so that the short tails do not win
+// by chance. The after part keeps at least 2 values.
+func (ts *TwoSampleDetector) split() int {
+	best, arg := math.Inf(-1), 0
+	for k := 0; k <= len(ts.win)-2; k++ {
+		before := append(append([]float64{}, ts.ref...), ts.win[:k]...)
+		after := ts.win[k:]
+		n, m := float64(len(before)), float64(len(after))
+		stat := ts.statistic(before, after)
+		if ts.test == KS {
+			stat *= math.Sqrt(n * m / (n + m))
+		} else {
+			stat *= n * m / (n + m)
+		}
+		if stat > best {
+			best, arg = stat, k
+		}
+	}
+	return arg
+}
+
+func (ts *TwoSampleDetector) statistic(a, b []float64) float64 {
+	if ts.test == KS {
+		return KSStatistic(a, b)
+	}
+	return MMDStatistic(a, b, ts.bandwidth)
+}
+
+// permutationPValue shuffles the pooled windows and counts the statistics at least as large.
+// For MMD the Gram matrix of the pooled windows is computed once.
+func (ts *TwoSampleDetector) permutationPValue() float64 {
+	pooled := append(append([]float64{}, ts.ref...), ts.win...)
+	n := len(ts.ref)
+	perm := make([]int, len(pooled))
+	for i := range perm {
+		perm[i] = i
+	}
+	var gram [][]float64
+	if ts.test == MMD {
+		gram = rbfGram(pooled, ts.bandwidth)
+	}
+	sample := make([]float64, len(pooled))
+	exceed := 0
+	for r := 0; r < ts.permutations; r++ {
+		ts.rng.Shuffle(len(perm), func(i, j int) { perm[i], perm[j] = perm[j], perm[i] })
+		var stat float64
+		if ts.test == MMD {
+			stat = mmdFromGram(gram, perm[:n], perm[n:])
+		} else {
+			for i, p := range perm {
+				sample[i] = pooled[p]
+			}
+			stat = KSStatistic(sample[:n], sample[n:])
+		}
+		if stat >= ts.stat {
+			exceed++
+		}
+	}
+	return float64(exceed+1) / float64(ts.permutations+1)
+}
+
+// State returns the last statistic and the reference estimate
+func (ts *TwoSampleDetector) State() State {
+	s := State{Step: ts.step, RunLength: ts.step - ts.lastCp, Statistic: ts.stat}
+	if len(ts.ref) > 0 {
+		var rs runningStats
+		for _, x := range ts.ref {
+			rs.add(x)
+		}
+		s.Mean, s.Variance = rs.mean, rs.variance()
+	}
+	return s
+}
+
+// PValue returns the p-value of the last test
+func (ts *TwoSampleDetector) PValue() float64 {
+	return ts.pvalue
+}
+
+// KSStatistic returns the largest distance between the empirical CDFs of a and b
+func KSStatistic(a, b []float64) float64 {
+	sa := append([]float64{}, a...)
+	sb := append([]float64{}, b...)
+	sort.Float64s(sa)
+	sort.Float64s(sb)
+	i, j, d := 0, 0, 0.0
+	for i < len(sa) && j < len(sb) {
+		x := math.Min(sa[i], sb[j])
+		for i < len(sa) && sa[i] == x {
+			i++
+		}
+		for j < len(sb) && sb[j] == x {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(sa))-float64(j)/float64(len(sb))))
+	}
+	return d
+}
+
+// KSPValue returns the asymptotic p-value of the KS statistic d, with the
+// small sample correction of Stephens: lambda = (sqrt(ne) + 0.12 + 0.11/sqrt(ne)) d
+func KSPValue(d float64, n, m int) float64 {
+	ne := math.Sqrt(float64(n*m) / float64(n+m))
+	lambda := (ne + 0.12 + 0.11/ne) * d
+	if lambda < 1e-3 {
+		return 1
+	}
+	// @ Q(lambda) = 2 sum (-1)^(k-1) exp(-2 k^2 lambda^2)
+	q, sign := 0.0, 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		q += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, q))
+}
+
+// MMDStatistic returns the unbiased squared MMD between a and b
+// with the RBF kernel of bandwidth h
+func MMDStatistic(a, b []float64, h float64) float64 {
+	pooled := append(append([]float64{}, a...), b...)
+	idx := make([]int, len(pooled))
+	for i := range idx {
+		idx[i] = i
+	}
+	return mmdFromGram(rbfGram(pooled, h), idx[:len(a)], idx[len(a):])
+}
+
+// rbfGram returns the RBF kernel matrix of the data
+func rbfGram(data []float64, h float64) [][]float64 {
+	gram := make([][]float64, len(data))
+	for i := range data {
+		gram[i] = make([]float64, len(data))
+		for j := range data {
+			d := (data[i] - data[j]) / h
+			gram[i][j] = math.Exp(-d * d / 2)
+		}
+	}
+	return gram
+}
+
+// mmdFromGram returns the unbiased squared MMD between the samples a and b,
+// given as the indices into the Gram matrix
+func mmdFromGram(gram [][]float64, a, b []int) float64 {
+	within := func(s []int) float64 {
+		sum := 0.0
+		for i := range s {
+			for j := i + 1; j < len(s); j++ {
+				sum += gram[s[i]][s[j]]
+			}
+		}
+		return 2 * sum / float64(len(s)*(len(s)-1))
+	}
+	between := 0.0
+	for _, i := range a {
+		for _, j := range b {
+			between += gram[i][j]
+		}
+	}
+	return within(a) + within(b) - 2*between/float64(len(a)*len(b))
+}
+
+// LinearMMDPValue returns the asymptotic p-value of the linear time MMD of
+// Gretton et al. (2012), which is normal under the null hypothesis:
+// h = k(x1, x2) + k(y1, y2) - k(x1, y2) - k(x2, y1) over consecutive pairs,
+// both windows are cut to the same even length, the latest data are kept.
+func LinearMMDPValue(a, b []float64, h float64) float64 {
+	m := min(len(a), len(b)) / 2 * 2
+	if m < 4 {
+		return 1
+	}
+	a, b = a[len(a)-m:], b[len(b)-m:]
+	k := func(x, y float64) float64 {
+		d := (x - y) / h
+		return math.Exp(-d * d / 2)
+	}
+	var rs runningStats
+	for i := 0; i < m; i += 2 {
+		rs.add(k(a[i], a[i+1]) + k(b[i], b[i+1]) - k(a[i], b[i+1]) - k(a[i+1], b[i]))
+	}
+	if rs.variance() == 0 {
+		if rs.mean > 0 {
+			return 0
+		}
+		return 1
+	}
+	z := rs.mean / math.Sqrt(rs.variance()/float64(rs.n))
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
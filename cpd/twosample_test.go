@@ -0,0 +1,64 @@
+package cpd
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test the KS statistic and its p-value on known values
+func TestKSStatistic(t *testing.T) {
+	assert.InDelta(t, 0.0, KSStatistic([]float64{1, 2, 3}, []float64{3, 2, 1}), 1e-12)
+	assert.InDelta(t, 1.0, KSStatistic([]float64{1, 2, 3}, []float64{4, 5}), 1e-12)
+	assert.InDelta(t, 0.5, KSStatistic([]float64{1, 2, 3, 4}, []float64{3, 4, 5, 6}), 1e-12)
+	// lambda = 1.36 is the 5% critical value
+	ne := 50.0
+	d := 1.36 / (ne + 0.12 + 0.11/ne)
+	assert.InDelta(t, 0.05, KSPValue(d, 5000, 5000), 0.002)
+	assert.Equal(t, 1.0, KSPValue(0, 10, 10))
+}
+
+// test the streaming detectors on the mean shifts of ../data/data_output.csv
+func TestTwoSampleDetector(t *testing.T) {
+	data := MustReadData("../data/data_output.csv")[:200]
+	truth := []int{58, 132}
+	for _, test := range []TwoSampleTest{KS, MMD} {
+		d := NewTwoSampleDetector(test, 40, 20, 1e-4, 0, 1)
+		events := RunDetector(d, data)
+		assert.Equal(t, len(truth), len(events))
+		for i, e := range events {
+			// the index is the best split of the windows, at the change
+			assert.InDelta(t, truth[i], e.Index, 3)
+			assert.Equal(t, e.Detected-e.Index, e.Latency)
+			assert.Greater(t, e.Detected, truth[i])
+			assert.Less(t, e.Detected-truth[i], 20)
+			assert.Less(t, e.PValue, 1e-4)
+		}
+	}
+}
+
+// test the permutation p-values on a change of shape with the same mean
+func TestTwoSampleDetectorPermutation(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	data := make([]float64, 0, 500)
+	for i := 0; i < 300; i++ {
+		data = append(data, rng.NormFloat64())
+	}
+	for i := 0; i < 200; i++ {
+		mode := 1.5
+		if rng.Intn(2) == 0 {
+			mode = -1.5
+		}
+		data = append(data, mode+0.3*rng.NormFloat64())
+	}
+	for _, test := range []TwoSampleTest{KS, MMD} {
+		var d Detector = NewTwoSampleDetector(test, 100, 50, 0.01, 199, 1)
+		events := RunDetector(d, data)
+		assert.Equal(t, 1, len(events))
+		assert.InDelta(t, 325, events[0].Detected, 25)
+		// 1/200 is the smallest p-value of 199 permutations
+		assert.InDelta(t, 0.005, events[0].PValue, 1e-12)
+		assert.Equal(t, len(data), d.State().Step)
+	}
+}
@@ -0,0 +1,179 @@
+package datasets
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// go:embed cannot reach the files above the package, so the csv of the demo,
+// ../data/data_output.csv, has a copy in data/ here; TestLoad checks the two
+// copies are the same.
+//
+//go:embed data/*.csv
+var files embed.FS
+
+// * Series is a reference series with its metadata. Changepoints are the
+// * first indices of the new segments, as the Index of the events of cpd.
+type Series struct {
+	Name        string
+	Description string
+	// Source is the reference of the data and of the changepoints
+	Source string
+	// Unit is the unit of Data, and TimeUnit of Time
+	Unit, TimeUnit string
+	// Time is the time of each value, e.g. the year, or the step without a time column
+	Time         []float64
+	Data         []float64
+	Changepoints []int
+}
+
+// Len returns the number of values
+func (s Series) Len() int {
+	return len(s.Data)
+}
+
+// TimeOf returns the time of the changepoints
+func (s Series) TimeOf(cps []int) []float64 {
+	res := make([]float64, len(cps))
+	for i, cp := range cps {
+		res[i] = s.Time[cp]
+	}
+	return res
+}
+
+// entry is a series of the registry, file is empty when it is not bundled
+type entry struct {
+	file   string
+	series Series
+	reason string
+}
+
+// * registry holds the metadata of the series, the data is in data/*.csv:
+// * one value per line, or the time and the value.
+var registry = []entry{
+	{file: "data/nile.csv", series: Series{
+		Name:        "nile",
+		Description: "Annual flow of the Nile at Aswan, 1871-1970; the flow drops from 1899, after the first Aswan dam",
+		Source:      "Cobb (1978), Biometrika 65(2); R datasets::Nile",
+		Unit:        "10^8 m^3",
+		TimeUnit:    "year",
+		// 1899
+		Changepoints: []int{28},
+	}},
+	{file: "data/coal_mining.csv", series: Series{
+		Name:        "coal-mining",
+		Description: "Yearly counts of the British coal mining disasters, 1851-1962; the rate falls from about 3 to 1 a year",
+		Source:      "Jarrett (1979), Biometrika 66(1); change around 1890-91 in Raftery & Akman (1986) and Adams & MacKay (2007)",
+		Unit:        "disasters",
+		TimeUnit:    "year",
+		// 1891
+		Changepoints: []int{40},
+	}},
+	{file: "data/data_output.csv", series: Series{
+		Name:        "data_output",
+		Description: "Normal segments of the demo, GenerateNormalTimeSeries(5, 50, 1000, 100) as of data/data_output.csv, partition [58, 74, 117, 153, 137, 129, 188]",
+		Source:      "this repository",
+		TimeUnit:    "step",
+		// the cumulative partition
+		Changepoints: []int{58, 132, 249, 402, 539, 668},
+	}},
+	{series: Series{
+		Name:        "well-log",
+		Description: "Nuclear magnetic response of a drilled well, 4050 values",
+		Source:      "Ó Ruanaidh & Fitzgerald (1996); Adams & MacKay (2007) figure 1",
+	}, reason: "its published file is not part of this repository"},
+	{series: Series{
+		Name:        "dow-jones",
+		Description: "Daily returns of the Dow Jones industrial average, 1972-1975",
+		Source:      "Adams & MacKay (2007) figure 2",
+	}, reason: "its published file is not part of this repository"},
+}
+
+// Names returns the names of the bundled series
+func Names() []string {
+	var res []string
+	for _, e := range registry {
+		if e.file != "" {
+			res = append(res, e.series.Name)
+		}
+	}
+	return res
+}
+
+// Load returns the series with the name. The well-log and the Dow Jones
+// returns of Adams & MacKay are known but not bundled, Load tells so.
+func Load(name string) (Series, error) {
+	for _, e := range registry {
+		if e.series.Name != name {
+			continue
+		}
+		if e.file == "" {
+			return Series{}, fmt.Errorf("dataset %s is not bundled: %s", name, e.reason)
+		}
+		return load(e)
+	}
+	return Series{}, fmt.Errorf("unknown dataset %q, the datasets are %v", name, Names())
+}
+
+// MustLoad is Load for the bundled series, it panics on an error
+func MustLoad(name string) Series {
+	s, err := Load(name)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// All returns all the bundled series, in the order of Names
+func All() []Series {
+	names := Names()
+	res := make([]Series, len(names))
+	for i, name := range names {
+		res[i] = MustLoad(name)
+	}
+	return res
+}
+
+// Nile returns the annual flow of the Nile
+func Nile() Series { return MustLoad("nile") }
+
+// CoalMining returns the yearly counts of the coal mining disasters
+func CoalMining() Series { return MustLoad("coal-mining") }
+
+// DataOutput returns the series of the demo
+func DataOutput() Series { return MustLoad("data_output") }
+
+func load(e entry) (Series, error) {
+	raw, err := files.ReadFile(e.file)
+	if err != nil {
+		return Series{}, err
+	}
+	s := e.series
+	// @ copy the slices of the registry, the caller may change them
+	s.Changepoints = append([]int{}, s.Changepoints...)
+	for i, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) > 2 {
+			return Series{}, fmt.Errorf("%s:%d: expected 1 or 2 columns, got %d", e.file, i+1, len(fields))
+		}
+		values := make([]float64, len(fields))
+		for j, f := range fields {
+			if values[j], err = strconv.ParseFloat(strings.TrimSpace(f), 64); err != nil {
+				return Series{}, fmt.Errorf("%s:%d: %w", e.file, i+1, err)
+			}
+		}
+		t := float64(len(s.Data))
+		if len(values) == 2 {
+			t = values[0]
+		}
+		s.Time = append(s.Time, t)
+		s.Data = append(s.Data, values[len(values)-1])
+	}
+	return s, nil
+}
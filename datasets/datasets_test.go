@@ -0,0 +1,58 @@
+package datasets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/cpd"
+)
+
+func TestLoad(t *testing.T) {
+	assert.Equal(t, []string{"nile", "coal-mining", "data_output"}, Names())
+
+	nile := Nile()
+	assert.Equal(t, 100, nile.Len())
+	assert.Equal(t, 1871.0, nile.Time[0])
+	assert.Equal(t, 1970.0, nile.Time[99])
+	assert.InDelta(t, 919.35, cpd.SumSlice(nile.Data)/100, 1e-9)
+	assert.Equal(t, []float64{1899}, nile.TimeOf(nile.Changepoints))
+
+	coal := CoalMining()
+	assert.Equal(t, 112, coal.Len())
+	assert.Equal(t, 191.0, cpd.SumSlice(coal.Data))
+	assert.Equal(t, []float64{1891}, coal.TimeOf(coal.Changepoints))
+
+	// the bundled copy is the csv of the demo, the time is the step
+	demo := DataOutput()
+	assert.Equal(t, cpd.MustReadData("../data/data_output.csv"), demo.Data)
+	assert.Equal(t, 855.0, demo.Time[855])
+	assert.Equal(t, 3, len(All()))
+
+	// the caller owns the slices
+	nile.Changepoints[0] = 0
+	assert.Equal(t, []int{28}, Nile().Changepoints)
+
+	_, err := Load("well-log")
+	assert.ErrorContains(t, err, "not bundled")
+	_, err = Load("dow-jones")
+	assert.ErrorContains(t, err, "not bundled")
+	_, err = Load("nothing")
+	assert.ErrorContains(t, err, "unknown dataset")
+	assert.Panics(t, func() { MustLoad("well-log") })
+}
+
+// test the detectors find the published changepoints
+func TestChangepoints(t *testing.T) {
+	nile := Nile()
+	res := cpd.PELT(nile.Data, cpd.NewMeanCost(), cpd.Penalty{Kind: cpd.MBIC}, 5)
+	assert.Equal(t, nile.Changepoints, res.Changepoints())
+
+	coal := CoalMining()
+	res = cpd.PELT(coal.Data, cpd.NewPoissonCost(), cpd.Penalty{Kind: cpd.MBIC}, 5)
+	assert.Equal(t, 1, len(res.Changepoints()))
+	assert.InDelta(t, coal.Changepoints[0], res.Changepoints()[0], 3)
+
+	demo := DataOutput()
+	res = cpd.PELT(demo.Data, cpd.NewMeanVarCost(), cpd.Penalty{Kind: cpd.MBIC}, 5)
+	assert.Equal(t, demo.Changepoints, res.Changepoints())
+}
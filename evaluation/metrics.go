@@ -0,0 +1,198 @@
+package evaluation
+
+import (
+	"math"
+	"sort"
+)
+
+// * The metrics compare the predicted changepoints with the true ones.
+// * A changepoint is the first index of a new segment, as the Index of the
+// * events of cpd, so neither 0 nor n is a changepoint.
+
+// * Report gathers all the metrics of one series.
+type Report struct {
+	Precision, Recall, F1 float64
+	Covering, RandIndex   float64
+	Hausdorff             float64
+	// MeanDelay is the delay of the predictions, see MeanDelay
+	MeanDelay float64
+}
+
+// Evaluate computes all the metrics of one series with n values,
+// a prediction counts if it is at most margin away from a true changepoint
+func Evaluate(pred, truth []int, n, margin int) Report {
+	r := Report{
+		Covering:  Covering(pred, truth, n),
+		RandIndex: RandIndex(pred, truth, n),
+		Hausdorff: Hausdorff(pred, truth),
+		MeanDelay: MeanDelay(pred, truth, margin),
+	}
+	r.Precision, r.Recall, r.F1 = PrecisionRecall(pred, truth, margin)
+	return r
+}
+
+// PrecisionRecall returns the precision, the recall and the F1 score. The
+// true changepoints and the predictions at most margin away are matched one
+// to one with as many pairs as possible, so one prediction is never counted
+// twice and the order of the lists does not matter. Without predictions the
+// precision is 1, without true changepoints the recall is 1.
+func PrecisionRecall(pred, truth []int, margin int) (precision, recall, f1 float64) {
+	tp := float64(matches(pred, truth, margin))
+	precision, recall = 1, 1
+	if len(pred) > 0 {
+		precision = tp / float64(len(pred))
+	}
+	if len(truth) > 0 {
+		recall = tp / float64(len(truth))
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return precision, recall, f1
+}
+
+// matches returns the size of the maximum matching of the true changepoints
+// with the predictions at most margin away. The windows of the true
+// changepoints all have the same width, so in ascending order each one takes
+// the first free prediction inside it, which is the optimal assignment.
+func matches(pred, truth []int, margin int) int {
+	ps := sorted(pred)
+	count, j := 0, 0
+	for _, cp := range sorted(truth) {
+		for j < len(ps) && ps[j] < cp-margin {
+			j++
+		}
+		if j < len(ps) && ps[j] <= cp+margin {
+			count++
+			j++
+		}
+	}
+	return count
+}
+
+// Covering returns the covering of the true segmentation by the predicted one,
+// van den Burg & Williams (2020): the mean over the true segments, weighted by
+// their length, of the best Jaccard index with a predicted segment.
+// It is 0 for an empty series.
+func Covering(pred, truth []int, n int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	ps := bounds(pred, n)
+	res := 0.0
+	ts := bounds(truth, n)
+	for i := 0; i+1 < len(ts); i++ {
+		a, b := ts[i], ts[i+1]
+		best := 0.0
+		for j := 0; j+1 < len(ps); j++ {
+			c, d := ps[j], ps[j+1]
+			inter := min(b, d) - max(a, c)
+			if inter > 0 {
+				best = math.Max(best, float64(inter)/float64(max(b, d)-min(a, c)))
+			}
+		}
+		res += float64(b-a) * best
+	}
+	return res / float64(n)
+}
+
+// AnnotatedCovering returns the mean covering over the segmentations of
+// several annotators of the same series
+func AnnotatedCovering(pred []int, annotations [][]int, n int) float64 {
+	res := 0.0
+	for _, truth := range annotations {
+		res += Covering(pred, truth, n) / float64(len(annotations))
+	}
+	return res
+}
+
+// RandIndex returns the share of the pairs of values which both segmentations
+// put together or both put apart, from the contingency table of the segments
+func RandIndex(pred, truth []int, n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	pairs := func(k int) float64 { return float64(k) * float64(k-1) / 2 }
+	ps, ts := bounds(pred, n), bounds(truth, n)
+	together, predPairs, truePairs := 0.0, 0.0, 0.0
+	for i := 0; i+1 < len(ps); i++ {
+		predPairs += pairs(ps[i+1] - ps[i])
+	}
+	for j := 0; j+1 < len(ts); j++ {
+		truePairs += pairs(ts[j+1] - ts[j])
+		for i := 0; i+1 < len(ps); i++ {
+			if inter := min(ps[i+1], ts[j+1]) - max(ps[i], ts[j]); inter > 0 {
+				together += pairs(inter)
+			}
+		}
+	}
+	return 1 + (2*together-predPairs-truePairs)/pairs(n)
+}
+
+// Hausdorff returns the largest distance from a changepoint of one list to
+// the closest one of the other, 0 if both are empty and +Inf if one is
+func Hausdorff(pred, truth []int) float64 {
+	if len(pred) == 0 && len(truth) == 0 {
+		return 0
+	}
+	if len(pred) == 0 || len(truth) == 0 {
+		return math.Inf(1)
+	}
+	return math.Max(directed(pred, truth), directed(truth, pred))
+}
+
+func directed(a, b []int) float64 {
+	res := 0
+	for _, x := range a {
+		best := math.MaxInt
+		for _, y := range b {
+			best = min(best, abs(x-y))
+		}
+		res = max(res, best)
+	}
+	return float64(res)
+}
+
+// MeanDelay returns the mean delay from each true changepoint to the first
+// alarm in [cp, cp+margin], over the detected ones, NaN if none is detected.
+// Pass the steps of the alarms, e.g. the Confirmed of the events, to get the
+// detection delay of an online detector rather than its location error.
+func MeanDelay(alarms, truth []int, margin int) float64 {
+	alarms = sorted(alarms)
+	sum, count := 0, 0
+	for _, cp := range truth {
+		i := sort.SearchInts(alarms, cp)
+		if i < len(alarms) && alarms[i]-cp <= margin {
+			sum += alarms[i] - cp
+			count++
+		}
+	}
+	if count == 0 {
+		return math.NaN()
+	}
+	return float64(sum) / float64(count)
+}
+
+// bounds returns 0, the changepoints in (0, n) sorted without duplicates, and n
+func bounds(cps []int, n int) []int {
+	res := []int{0}
+	for _, cp := range sorted(cps) {
+		if cp > res[len(res)-1] && cp < n {
+			res = append(res, cp)
+		}
+	}
+	return append(res, n)
+}
+
+func sorted(x []int) []int {
+	res := append([]int{}, x...)
+	sort.Ints(res)
+	return res
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,58 @@
+package evaluation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecisionRecall(t *testing.T) {
+	p, r, f := PrecisionRecall([]int{10, 52, 90}, []int{50, 100}, 5)
+	assert.InDelta(t, 1.0/3, p, 1e-12)
+	assert.InDelta(t, 0.5, r, 1e-12)
+	assert.InDelta(t, 0.4, f, 1e-12)
+	// one prediction matches one true changepoint only
+	p, r, _ = PrecisionRecall([]int{50}, []int{48, 52}, 5)
+	assert.Equal(t, 1.0, p)
+	assert.Equal(t, 0.5, r)
+	// the closest prediction of 10 is 11, but 8 leaves 11 to 13
+	p, r, _ = PrecisionRecall([]int{11, 8}, []int{10, 13}, 2)
+	assert.Equal(t, []float64{1, 1}, []float64{p, r})
+	p2, r2, _ := PrecisionRecall([]int{8, 11}, []int{13, 10}, 2)
+	assert.Equal(t, []float64{p, r}, []float64{p2, r2})
+	p, r, f = PrecisionRecall(nil, nil, 5)
+	assert.Equal(t, []float64{1, 1, 1}, []float64{p, r, f})
+	_, r, f = PrecisionRecall(nil, []int{3}, 5)
+	assert.Equal(t, []float64{0, 0}, []float64{r, f})
+}
+
+func TestCoveringAndRandIndex(t *testing.T) {
+	assert.Equal(t, 1.0, Covering([]int{5}, []int{5}, 10))
+	assert.Equal(t, 1.0, RandIndex([]int{5}, []int{5}, 10))
+	// two halves covered by one segment
+	assert.InDelta(t, 0.5, Covering(nil, []int{5}, 10), 1e-12)
+	assert.InDelta(t, 20.0/45, RandIndex(nil, []int{5}, 10), 1e-12)
+	// [0,5) [5,10) covered by [0,4) [4,10): 5 * 4/5 + 5 * 5/6 over 10
+	assert.InDelta(t, (5*0.8+5*5.0/6)/10, Covering([]int{4}, []int{5}, 10), 1e-12)
+	// the pairs are symmetric
+	assert.InDelta(t, RandIndex([]int{4}, []int{5}, 10), RandIndex([]int{5}, []int{4}, 10), 1e-12)
+	assert.InDelta(t, (Covering([]int{4}, []int{5}, 10)+1)/2, AnnotatedCovering([]int{4}, [][]int{{5}, {4}}, 10), 1e-12)
+	// the edges and the duplicates are ignored
+	assert.Equal(t, 1.0, Covering([]int{0, 5, 5, 10}, []int{5}, 10))
+	// an empty series is not covered
+	assert.Equal(t, 0.0, Covering(nil, nil, 0))
+	assert.Equal(t, 0.0, Covering([]int{3}, []int{2}, -1))
+}
+
+func TestHausdorffAndDelay(t *testing.T) {
+	assert.Equal(t, 48.0, Hausdorff([]int{10, 52}, []int{50, 100}))
+	assert.Equal(t, 0.0, Hausdorff(nil, nil))
+	assert.True(t, math.IsInf(Hausdorff(nil, []int{1}), 1))
+
+	assert.Equal(t, 4.0, MeanDelay([]int{103, 55, 30}, []int{50, 100}, 10))
+	assert.True(t, math.IsNaN(MeanDelay([]int{30}, []int{50}, 10)))
+
+	r := Evaluate([]int{50, 100}, []int{50, 100}, 200, 5)
+	assert.Equal(t, Report{Precision: 1, Recall: 1, F1: 1, Covering: 1, RandIndex: 1}, r)
+}
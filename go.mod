@@ -5,11 +5,11 @@ go 1.21.5
 require (
 	github.com/stretchr/testify v1.9.0
 	gonum.org/v1/gonum v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,14 +2,29 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/wonderstone/change-point-detection/benchmark"
 	"github.com/wonderstone/change-point-detection/cpd"
 )
 
 func main() {
+	// subcommands, the default is the demo below
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		simulate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		bench(os.Args[2:])
+		return
+	}
 
 	// Step 1: generate the data
 	num := 5
@@ -60,6 +75,133 @@ func main() {
 	WriteData(c.Maxes, "data_output.csv")
 }
 
+// simulate writes the series of a scenario file and optionally its ground truth:
+// go run . simulate -spec data/scenarios/data_input.yaml -out data_input.csv -truth truth.json
+func simulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	spec := fs.String("spec", "", "the scenario file, .json or .yaml")
+	out := fs.String("out", "data_input.csv", "the csv file of the series")
+	truth := fs.String("truth", "", "the json file of the changepoints and the segments")
+	fs.Parse(args)
+	if *spec == "" {
+		log.Fatal("simulate needs -spec")
+	}
+	scenario, err := cpd.LoadScenario(*spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sim, err := scenario.Simulate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if sim.Series != nil {
+		log.Fatal("simulate only writes univariate scenarios")
+	}
+	WriteData(sim.Data, *out)
+	if *truth != "" {
+		data, err := json.MarshalIndent(struct {
+			Changepoints      []int
+			Segments          []cpd.Segment
+			Outliers, Missing []int
+		}{sim.Changepoints, sim.Segments, sim.Outliers, sim.Missing}, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*truth, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("%s: %d values, changepoints %v\n", *out, len(sim.Data), sim.Changepoints)
+}
+
+// bench runs the detectors over the datasets and writes the comparison table:
+// go run . bench -csv data/data_output.csv -scenarios data/scenarios -format markdown
+func bench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	scenarios := fs.String("scenarios", "", "comma separated scenario files or directories of them")
+	csvs := fs.String("csv", "", "comma separated csv files, each with its .annotations.json")
+	synthetic := fs.Bool("synthetic", true, "include the default synthetic scenarios")
+	embedded := fs.String("embedded", "all", "comma separated bundled datasets, all of them with all, none with an empty list")
+	seed := fs.Int64("seed", 1, "the seed of the default synthetic scenarios")
+	methods := fs.String("methods", "", "comma separated methods, all of them if empty")
+	format := fs.String("format", "markdown", "markdown, csv or json")
+	out := fs.String("out", "", "the output file, stdout if empty")
+	margin := fs.Int("margin", 5, "the margin of precision and recall")
+	delay := fs.Int("delay", 50, "the horizon of the detection delay")
+	fs.Parse(args)
+
+	var datasets []benchmark.Dataset
+	if *synthetic {
+		datasets = append(datasets, benchmark.DefaultScenarios(*seed)...)
+	}
+	if names := splitList(*embedded); names != nil {
+		if len(names) == 1 && names[0] == "all" {
+			names = nil
+		}
+		bundled, err := benchmark.Embedded(names...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		datasets = append(datasets, bundled...)
+	}
+	for _, path := range splitList(*scenarios) {
+		files := []string{path}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			files = nil
+			for _, ext := range []string{"*.json", "*.yaml", "*.yml"} {
+				matches, _ := filepath.Glob(filepath.Join(path, ext))
+				files = append(files, matches...)
+			}
+		}
+		for _, file := range files {
+			ds, err := benchmark.LoadScenario(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			datasets = append(datasets, ds)
+		}
+	}
+	for _, path := range splitList(*csvs) {
+		ds, err := benchmark.LoadCSV(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		datasets = append(datasets, ds)
+	}
+	datasets = benchmark.Unique(datasets)
+	if len(datasets) == 0 {
+		log.Fatal("bench needs at least one dataset")
+	}
+	ms := benchmark.DefaultMethods()
+	if names := splitList(*methods); names != nil {
+		ms = benchmark.SelectMethods(ms, names)
+		if len(ms) != len(names) {
+			log.Fatalf("unknown method in %v", names)
+		}
+	}
+
+	rows := benchmark.Run(datasets, ms, benchmark.Config{Margin: *margin, DelayMargin: *delay})
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := benchmark.Write(w, rows, *format); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitList splits a comma separated flag, nil if empty
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
 
 // func to write the slice of float64 to the csv file
 func WriteData(data []float64, filename string) {
@@ -0,0 +1,167 @@
+package tuning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/wonderstone/change-point-detection/cpd"
+)
+
+// * Config is one setting of OCPD: the hazard lam, the Normal-Gamma prior
+// * of the Student-t model and the confirmation of the event layer.
+// * cpd.NewOCPD(c.Args()) builds the detector, c.NewOCPD() also sets the
+// * confirmation.
+type Config struct {
+	Lam       float64 `json:"lam"`
+	Alpha     float64 `json:"alpha"`
+	Beta      float64 `json:"beta"`
+	Kappa     float64 `json:"kappa"`
+	Mu        float64 `json:"mu"`
+	Window    int     `json:"window"`
+	Threshold float64 `json:"threshold"`
+}
+
+// Model returns a fresh Student-t model with the prior of the config
+func (c Config) Model() *cpd.StudentT_Bayesian_Update {
+	return cpd.NewStudentT_BU([]float64{c.Alpha}, []float64{c.Beta}, []float64{c.Kappa}, []float64{c.Mu})
+}
+
+// Args returns the arguments of cpd.NewOCPD, with the constant hazard
+func (c Config) Args() (float64, func(float64, []float64) []float64, cpd.ObservationModel) {
+	return c.Lam, cpd.ConstantHazardSlice, c.Model()
+}
+
+// NewOCPD returns the detector of the config, confirmation included
+func (c Config) NewOCPD() *cpd.OCPD {
+	return cpd.NewOCPD(c.Args()).SetConfirmation(c.Window, c.Threshold)
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("lam=%g alpha=%g beta=%g kappa=%g mu=%g window=%d threshold=%g",
+		c.Lam, c.Alpha, c.Beta, c.Kappa, c.Mu, c.Window, c.Threshold)
+}
+
+// * Range is the values of one parameter. The grid search takes Values, the
+// * random search picks one of Values, or draws in [Min, Max] without
+// * Values, on the log scale if Log.
+type Range struct {
+	Values   []float64
+	Min, Max float64
+	Log      bool
+}
+
+// Fixed returns the range of a parameter which is not searched
+func Fixed(x float64) Range {
+	return Range{Values: []float64{x}, Min: x, Max: x}
+}
+
+func (r Range) draw(rng *rand.Rand) float64 {
+	if len(r.Values) > 0 {
+		return r.Values[rng.Intn(len(r.Values))]
+	}
+	if r.Log {
+		return math.Exp(math.Log(r.Min) + rng.Float64()*(math.Log(r.Max)-math.Log(r.Min)))
+	}
+	return r.Min + rng.Float64()*(r.Max-r.Min)
+}
+
+func (r Range) check(name string, positive bool) {
+	for _, v := range r.Values {
+		if positive && v <= 0 {
+			panic(fmt.Sprintf("the values of %s must be positive", name))
+		}
+	}
+	if len(r.Values) > 0 {
+		return
+	}
+	if r.Max < r.Min {
+		panic(fmt.Sprintf("the range of %s needs Min <= Max", name))
+	}
+	if (positive || r.Log) && r.Min <= 0 {
+		panic(fmt.Sprintf("the range of %s must be positive", name))
+	}
+}
+
+// * Space is the search space over the hazard and the prior. The
+// * confirmation Window and Threshold are not searched, every config has them.
+type Space struct {
+	Lam, Alpha, Beta, Kappa, Mu Range
+	Window                      int
+	Threshold                   float64
+}
+
+// DefaultSpace is around the setting of the demo: lam 250 and the prior
+// alpha 0.1, beta 0.01, kappa 1, mu 0, with a 25 steps confirmation
+func DefaultSpace() Space {
+	return Space{
+		Lam:       Range{Values: []float64{50, 100, 250, 500, 1000}, Min: 10, Max: 2000, Log: true},
+		Alpha:     Range{Values: []float64{0.1, 1, 10}, Min: 0.01, Max: 100, Log: true},
+		Beta:      Range{Values: []float64{0.01, 0.1, 1, 10}, Min: 0.001, Max: 100, Log: true},
+		Kappa:     Range{Values: []float64{0.1, 1}, Min: 0.01, Max: 10, Log: true},
+		Mu:        Fixed(0),
+		Window:    25,
+		Threshold: 0.5,
+	}
+}
+
+func (s Space) check() {
+	s.Lam.check("lam", true)
+	s.Alpha.check("alpha", true)
+	s.Beta.check("beta", true)
+	s.Kappa.check("kappa", true)
+	s.Mu.check("mu", false)
+	if s.Window < 0 || s.Threshold < 0 || s.Threshold > 1 {
+		panic("window must be non-negative and threshold must be in [0, 1]")
+	}
+}
+
+// Grid returns all the combinations of the Values, lam varies the slowest
+func Grid(s Space) []Config {
+	s.check()
+	ranges := []Range{s.Lam, s.Alpha, s.Beta, s.Kappa, s.Mu}
+	for i, name := range []string{"lam", "alpha", "beta", "kappa", "mu"} {
+		if len(ranges[i].Values) == 0 {
+			panic(fmt.Sprintf("the grid needs the values of %s", name))
+		}
+	}
+	res := []Config{{Window: s.Window, Threshold: s.Threshold}}
+	set := []func(c *Config, v float64){
+		func(c *Config, v float64) { c.Lam = v },
+		func(c *Config, v float64) { c.Alpha = v },
+		func(c *Config, v float64) { c.Beta = v },
+		func(c *Config, v float64) { c.Kappa = v },
+		func(c *Config, v float64) { c.Mu = v },
+	}
+	// @ expand one parameter after the other
+	for i, r := range ranges {
+		next := make([]Config, 0, len(res)*len(r.Values))
+		for _, c := range res {
+			for _, v := range r.Values {
+				set[i](&c, v)
+				next = append(next, c)
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+// Random returns n configs drawn from the space, the same seed gives the same configs
+func Random(s Space, n int, seed int64) []Config {
+	s.check()
+	rng := rand.New(rand.NewSource(seed))
+	res := make([]Config, n)
+	for i := range res {
+		res[i] = Config{
+			Lam:       s.Lam.draw(rng),
+			Alpha:     s.Alpha.draw(rng),
+			Beta:      s.Beta.draw(rng),
+			Kappa:     s.Kappa.draw(rng),
+			Mu:        s.Mu.draw(rng),
+			Window:    s.Window,
+			Threshold: s.Threshold,
+		}
+	}
+	return res
+}
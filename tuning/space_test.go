@@ -0,0 +1,68 @@
+package tuning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/cpd"
+)
+
+func TestGrid(t *testing.T) {
+	s := Space{
+		Lam: Range{Values: []float64{100, 250}}, Alpha: Fixed(0.1), Beta: Range{Values: []float64{0.01, 1}},
+		Kappa: Fixed(1), Mu: Fixed(0), Window: 25, Threshold: 0.5,
+	}
+	configs := Grid(s)
+	assert.Equal(t, 4, len(configs))
+	// lam varies the slowest
+	assert.Equal(t, Config{Lam: 100, Alpha: 0.1, Beta: 0.01, Kappa: 1, Window: 25, Threshold: 0.5}, configs[0])
+	assert.Equal(t, Config{Lam: 100, Alpha: 0.1, Beta: 1, Kappa: 1, Window: 25, Threshold: 0.5}, configs[1])
+	assert.Equal(t, 250.0, configs[2].Lam)
+	assert.Equal(t, 120, len(Grid(DefaultSpace())))
+
+	s.Beta = Range{Min: 0.01, Max: 1}
+	assert.Panics(t, func() { Grid(s) })
+	s.Beta = Range{Values: []float64{0}}
+	assert.Panics(t, func() { Grid(s) })
+}
+
+func TestRandom(t *testing.T) {
+	s := DefaultSpace()
+	s.Lam = Range{Min: 10, Max: 2000, Log: true}
+	s.Mu = Range{Min: -1, Max: 1}
+	configs := Random(s, 200, 1)
+	assert.Equal(t, configs, Random(s, 200, 1))
+	assert.NotEqual(t, configs, Random(s, 200, 2))
+	small := 0
+	for _, c := range configs {
+		assert.True(t, c.Lam >= 10 && c.Lam <= 2000)
+		assert.True(t, c.Mu >= -1 && c.Mu <= 1)
+		assert.Contains(t, s.Alpha.Values, c.Alpha)
+		if c.Lam < 141 {
+			small++
+		}
+	}
+	// the log scale draws half of lam below the geometric mean
+	assert.InDelta(t, 100, small, 25)
+
+	s.Lam = Range{Min: 0, Max: 10, Log: true}
+	assert.Panics(t, func() { Random(s, 1, 1) })
+}
+
+// test a config builds the same detector as the demo
+func TestConfigOCPD(t *testing.T) {
+	_, data := cpd.GenerateNormalTimeSeries(3, 50, 200, 1)
+	c := Config{Lam: 250, Alpha: 0.1, Beta: 0.01, Kappa: 1, Mu: 0, Window: 0, Threshold: 0.5}
+	a := cpd.NewOCPD(c.Args())
+	b := cpd.NewOCPD(250, cpd.ConstantHazardSlice, cpd.NewStudentT_BU([]float64{0.1}, []float64{0.01}, []float64{1}, []float64{0})).SetConfirmation(0, 0.5)
+	d := c.NewOCPD()
+	for _, x := range data {
+		a.OCPD_Update(x)
+		b.OCPD_Update(x)
+		d.OCPD_Update(x)
+	}
+	assert.Equal(t, b.Maxes, a.Maxes)
+	assert.Empty(t, a.Events)
+	assert.Equal(t, b.Events, d.Events)
+	assert.Equal(t, "lam=250 alpha=0.1 beta=0.01 kappa=1 mu=0 window=0 threshold=0.5", c.String())
+}
@@ -0,0 +1,128 @@
+package tuning
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/wonderstone/change-point-detection/cpd"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+// * Metric ranks the configs by one value of the evaluation report.
+type Metric struct {
+	Name  string
+	Value func(r evaluation.Report) float64
+	// Lower is true when the lower value is the better, e.g. a distance
+	Lower bool
+}
+
+var (
+	F1        = Metric{Name: "f1", Value: func(r evaluation.Report) float64 { return r.F1 }}
+	Precision = Metric{Name: "precision", Value: func(r evaluation.Report) float64 { return r.Precision }}
+	Recall    = Metric{Name: "recall", Value: func(r evaluation.Report) float64 { return r.Recall }}
+	Covering  = Metric{Name: "covering", Value: func(r evaluation.Report) float64 { return r.Covering }}
+	RandIndex = Metric{Name: "rand_index", Value: func(r evaluation.Report) float64 { return r.RandIndex }}
+	Hausdorff = Metric{Name: "hausdorff", Value: func(r evaluation.Report) float64 { return r.Hausdorff }, Lower: true}
+	MeanDelay = Metric{Name: "mean_delay", Value: func(r evaluation.Report) float64 { return r.MeanDelay }, Lower: true}
+)
+
+// better tells if the value a ranks before b, NaN ranks last
+func (m Metric) better(a, b float64) bool {
+	if math.IsNaN(b) {
+		return !math.IsNaN(a)
+	}
+	if m.Lower {
+		return a < b
+	}
+	return a > b
+}
+
+// * Options is the setting of a search.
+type Options struct {
+	Metric Metric
+	// Margin is the tolerance of precision, recall and F1
+	Margin int
+	// DelayMargin is the horizon of the detection delay, from the Confirmed steps
+	DelayMargin int
+	// Workers is the number of goroutines
+	Workers int
+}
+
+// DefaultOptions ranks by F1 with a 5 steps margin and a 50 steps delay horizon, on all the CPUs
+func DefaultOptions() Options {
+	return Options{Metric: F1, Margin: 5, DelayMargin: 50, Workers: runtime.NumCPU()}
+}
+
+// * Trial is the outcome of one config on the training series.
+type Trial struct {
+	Config       Config
+	Changepoints []int
+	Report       evaluation.Report
+	// Score is the value of the metric
+	Score float64
+}
+
+// Tune runs OCPD with every config over the labelled series and returns the
+// trials, the best first. The configs run in parallel on opts.Workers
+// goroutines; the ranking does not depend on it, equal scores keep the
+// order of the configs.
+func Tune(data []float64, truth []int, configs []Config, opts Options) []Trial {
+	if opts.Metric.Value == nil {
+		panic("the metric is not set")
+	}
+	workers := max(1, min(opts.Workers, len(configs)))
+	trials := make([]Trial, len(configs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// @ each trial has its own detector and model, the data is only read
+			for i := range jobs {
+				trials[i] = run(data, truth, configs[i], opts)
+			}
+		}()
+	}
+	for i := range configs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		return opts.Metric.better(trials[i].Score, trials[j].Score)
+	})
+	return trials
+}
+
+// GridSearch tunes over the grid of the space, see Grid and Tune
+func GridSearch(data []float64, truth []int, s Space, opts Options) []Trial {
+	return Tune(data, truth, Grid(s), opts)
+}
+
+// RandomSearch tunes over n configs drawn from the space, see Random and Tune
+func RandomSearch(data []float64, truth []int, s Space, n int, seed int64, opts Options) []Trial {
+	return Tune(data, truth, Random(s, n, seed), opts)
+}
+
+func run(data []float64, truth []int, c Config, opts Options) Trial {
+	d := c.NewOCPD()
+	for _, x := range data {
+		d.OCPD_Update(x)
+	}
+	res := d.Result()
+	alarms := make([]int, 0, len(res.Events))
+	for _, e := range res.Events {
+		if e.Kind == cpd.Changepoint {
+			alarms = append(alarms, e.Confirmed)
+		}
+	}
+	t := Trial{Config: c, Changepoints: res.Changepoints()}
+	t.Report = evaluation.Evaluate(t.Changepoints, truth, len(data), opts.Margin)
+	t.Report.MeanDelay = evaluation.MeanDelay(alarms, truth, opts.DelayMargin)
+	t.Score = opts.Metric.Value(t.Report)
+	return t
+}
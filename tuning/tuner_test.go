@@ -0,0 +1,56 @@
+package tuning
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonderstone/change-point-detection/cpd"
+	"github.com/wonderstone/change-point-detection/evaluation"
+)
+
+func TestGridSearch(t *testing.T) {
+	sim := cpd.SimulateMeanShift([]int{150, 200, 120, 180}, []float64{0, 4, -2, 3}, 1, 1)
+	s := Space{
+		Lam:   Range{Values: []float64{50, 1000}},
+		Alpha: Range{Values: []float64{0.1, 10}},
+		Beta:  Range{Values: []float64{0.1, 10}},
+		Kappa: Fixed(0.1), Mu: Fixed(0), Window: 25, Threshold: 0.5,
+	}
+	opts := DefaultOptions()
+	opts.Workers = 4
+	trials := GridSearch(sim.Data, sim.Changepoints, s, opts)
+	assert.Equal(t, 8, len(trials))
+	for i := 1; i < len(trials); i++ {
+		assert.GreaterOrEqual(t, trials[i-1].Score, trials[i].Score)
+	}
+	// the vague prior finds all the changes, a strong prior on a small variance does not
+	best := trials[0]
+	assert.Equal(t, 1.0, best.Score)
+	assert.Equal(t, sim.Changepoints, best.Changepoints)
+	assert.Less(t, trials[len(trials)-1].Score, 1.0)
+
+	// the best config reproduces its trial
+	d := best.Config.NewOCPD()
+	for _, x := range sim.Data {
+		d.OCPD_Update(x)
+	}
+	assert.Equal(t, best.Changepoints, d.Result().Changepoints())
+
+	// the number of workers does not change the ranking
+	opts.Workers = 1
+	assert.Equal(t, trials, GridSearch(sim.Data, sim.Changepoints, s, opts))
+
+	// the random search over the same values
+	random := RandomSearch(sim.Data, sim.Changepoints, s, 3, 1, opts)
+	assert.Equal(t, 3, len(random))
+}
+
+func TestMetric(t *testing.T) {
+	assert.True(t, F1.better(1, 0.5))
+	assert.True(t, Hausdorff.better(3, 10))
+	assert.False(t, Hausdorff.better(math.NaN(), 10))
+	assert.True(t, MeanDelay.better(10, math.NaN()))
+	assert.Equal(t, 4.0, MeanDelay.Value(evaluation.Report{MeanDelay: 4}))
+	assert.Panics(t, func() { Tune(nil, nil, []Config{{}}, Options{}) })
+}